@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/shopspring/decimal"
+
+	"finorbit/platform/alerts"
+	"finorbit/platform/logging"
+)
+
+// =========================================================
+// 🚨 Alertas operacionais
+// =========================================================
+// Envio, limitador e fila ficam em finorbit/platform/alerts; aqui só os
+// gatilhos do consumer. Os alertas saem em segundo plano e o handler
+// espera os pendentes no fim do lote (flushAlerts).
+const (
+	alertPoisonMessage   = "poison_message"
+	alertDBFailures      = "db_failures"
+	alertLargeWithdrawal = "large_withdrawal_rejected"
+
+	dbFailureThreshold     = 3
+	defaultLargeWithdrawal = 10000
+)
+
+var alerter alerts.Alerter = alerts.Log{}
+
+// flushAlerts espera os alertas do lote; o que não sair a tempo segue na
+// fila para a próxima invocação.
+func flushAlerts(ctx context.Context) {
+	if err := alerts.Flush(ctx, alerter); err != nil {
+		slog.WarnContext(ctx, "Alertas ainda pendentes no fim da invocação", logKeyError, err)
+	}
+}
+
+// =========================================================
+// 🧪 Gatilhos
+// =========================================================
+
+// alertPoison avisa sobre uma mensagem que seguirá para a DLQ. O corpo
+// nunca vai no alerta — só a origem e o motivo.
+func alertPoison(ctx context.Context, record events.SQSMessage, err error) {
+	alerter.Alert(ctx, alerts.Alert{
+		Kind:     alertPoisonMessage,
+		Severity: alerts.SeverityWarning,
+		Summary:  "Mensagem venenosa descartada",
+		Details: map[string]string{
+			"message_id":        record.MessageId,
+			"queue":             record.EventSourceARN,
+			logKeyError:         err.Error(),
+			logKeyCorrelationID: logging.CorrelationIDFromContext(ctx),
+		},
+		DedupKey: record.EventSourceARN,
+	})
+}
+
+// dbFailures conta falhas temporárias consecutivas; um sucesso zera a
+// sequência. O alerta sai a partir de dbFailureThreshold falhas seguidas.
+var dbFailures failureStreak
+
+type failureStreak struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (s *failureStreak) Fail() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	return s.count
+}
+
+func (s *failureStreak) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count = 0
+}
+
+func recordDBFailure(ctx context.Context, err error) {
+	count := dbFailures.Fail()
+	if count < dbFailureThreshold {
+		return
+	}
+	alerter.Alert(ctx, alerts.Alert{
+		Kind:     alertDBFailures,
+		Severity: alerts.SeverityCritical,
+		Summary:  "Falhas repetidas ao acessar o banco",
+		Details: map[string]string{
+			"consecutive_failures": fmt.Sprint(count),
+			logKeyError:            err.Error(),
+		},
+		DedupKey: "db",
+	})
+}
+
+// largeWithdrawalThreshold é o valor a partir do qual um saque recusado
+// por saldo insuficiente vira alerta (LARGE_WITHDRAWAL_THRESHOLD).
+var largeWithdrawalThreshold = decimal.NewFromInt(defaultLargeWithdrawal)
+
+func largeWithdrawalThresholdFromEnv() (decimal.Decimal, error) {
+	raw := os.Getenv("LARGE_WITHDRAWAL_THRESHOLD")
+	if raw == "" {
+		return decimal.NewFromInt(defaultLargeWithdrawal), nil
+	}
+	threshold, err := decimal.NewFromString(raw)
+	if err != nil || threshold.LessThanOrEqual(decimal.Zero) {
+		return decimal.Decimal{}, fmt.Errorf("LARGE_WITHDRAWAL_THRESHOLD inválido: %q", raw)
+	}
+	return threshold, nil
+}
+
+// alertRejectedWithdrawal avisa sobre saques grandes recusados, com a
+// conta mascarada como nos logs.
+func alertRejectedWithdrawal(ctx context.Context, tx Transaction) {
+	if tx.Amount.LessThan(largeWithdrawalThreshold) {
+		return
+	}
+	alerter.Alert(ctx, alerts.Alert{
+		Kind:     alertLargeWithdrawal,
+		Severity: alerts.SeverityWarning,
+		Summary:  "Saque de valor alto recusado por saldo insuficiente",
+		Details: map[string]string{
+			logKeyEventID:       tx.EventID,
+			logKeyUserID:        logging.MaskAccountID(tx.UserID),
+			logKeyAmount:        tx.Amount.String(),
+			logKeyCorrelationID: logging.CorrelationIDFromContext(ctx),
+		},
+		DedupKey: tx.UserID,
+	})
+}
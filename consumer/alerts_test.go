@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/shopspring/decimal"
+
+	"finorbit/platform/alerts"
+)
+
+// recordingAlerter guarda os alertas em vez de publicá-los.
+type recordingAlerter struct {
+	alerts []alerts.Alert
+}
+
+func (r *recordingAlerter) Alert(_ context.Context, alert alerts.Alert) {
+	r.alerts = append(r.alerts, alert)
+}
+
+func (r *recordingAlerter) kinds() []string {
+	var kinds []string
+	for _, a := range r.alerts {
+		kinds = append(kinds, a.Kind)
+	}
+	return kinds
+}
+
+// useRecordingAlerter troca o alerter global e zera a sequência de falhas.
+func useRecordingAlerter(t *testing.T) *recordingAlerter {
+	t.Helper()
+	rec := &recordingAlerter{}
+	saved := alerter
+	alerter = rec
+	dbFailures.Reset()
+	t.Cleanup(func() {
+		alerter = saved
+		dbFailures.Reset()
+	})
+	return rec
+}
+
+// =========================================================
+// 🧪 Gatilhos
+// =========================================================
+func TestProcessRecord_MensagemVenenosaAlertaSemCorpo(t *testing.T) {
+	rec := useRecordingAlerter(t)
+
+	record := events.SQSMessage{MessageId: "veneno", EventSourceARN: "arn:aws:sqs:us-east-1:123:deposit", Body: "segredo-do-cliente"}
+	if err := processRecord(context.Background(), nil, record); !isPermanent(err) {
+		t.Fatalf("Esperava falha permanente, obteve %v", err)
+	}
+
+	if len(rec.alerts) != 1 || rec.alerts[0].Kind != alertPoisonMessage {
+		t.Fatalf("Esperava 1 alerta de mensagem venenosa, obteve %v", rec.kinds())
+	}
+	alert := rec.alerts[0]
+	if alert.DedupKey != record.EventSourceARN || alert.Details["message_id"] != "veneno" {
+		t.Errorf("Alerta sem a origem da mensagem: %+v", alert)
+	}
+	for _, v := range alert.Details {
+		if strings.Contains(v, "segredo-do-cliente") {
+			t.Errorf("Corpo da mensagem vazou no alerta: %+v", alert.Details)
+		}
+	}
+}
+
+func TestHandler_FalhasSeguidasDoBancoAlertam(t *testing.T) {
+	rec := useRecordingAlerter(t)
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	body, _ := snsEnvelope(rawEvent, nil)
+	var records []events.SQSMessage
+	for i := 0; i < dbFailureThreshold; i++ {
+		mock.ExpectBegin().WillReturnError(errors.New("connection reset"))
+		records = append(records, events.SQSMessage{MessageId: string(rune('a' + i)), Body: body})
+	}
+
+	if _, err := handler(context.Background(), events.SQSEvent{Records: records[:dbFailureThreshold-1]}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if len(rec.alerts) != 0 {
+		t.Fatalf("Não deveria alertar antes de %d falhas, obteve %v", dbFailureThreshold, rec.kinds())
+	}
+
+	handler(context.Background(), events.SQSEvent{Records: records[dbFailureThreshold-1:]})
+	if len(rec.alerts) != 1 || rec.alerts[0].Kind != alertDBFailures || rec.alerts[0].Severity != alerts.SeverityCritical {
+		t.Errorf("Esperava 1 alerta crítico de banco, obteve %+v", rec.alerts)
+	}
+}
+
+func TestProcessRecord_SucessoZeraSequenciaDeFalhas(t *testing.T) {
+	useRecordingAlerter(t)
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	body, _ := snsEnvelope(rawEvent, nil)
+	mock.ExpectBegin().WillReturnError(errors.New("connection reset"))
+	expectLedgerPosting(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "10", "-10")
+
+	processRecord(context.Background(), dbMock, events.SQSMessage{Body: body})
+	if err := processRecord(context.Background(), dbMock, events.SQSMessage{Body: body}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if got := dbFailures.Fail(); got != 1 {
+		t.Errorf("Sucesso deveria zerar a sequência, próxima falha contou %d", got)
+	}
+}
+
+func TestAlertRejectedWithdrawal_SoValoresAltos(t *testing.T) {
+	rec := useRecordingAlerter(t)
+	saved := largeWithdrawalThreshold
+	largeWithdrawalThreshold = decimal.NewFromInt(1000)
+	t.Cleanup(func() { largeWithdrawalThreshold = saved })
+
+	alertRejectedWithdrawal(context.Background(), Transaction{UserID: "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", Amount: decimal.RequireFromString("999.99")})
+	alertRejectedWithdrawal(context.Background(), Transaction{UserID: "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", Amount: decimal.RequireFromString("1000")})
+
+	if len(rec.alerts) != 1 || rec.alerts[0].Kind != alertLargeWithdrawal {
+		t.Fatalf("Esperava 1 alerta de saque alto, obteve %v", rec.kinds())
+	}
+	if user := rec.alerts[0].Details[logKeyUserID]; strings.Contains("6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", user) {
+		t.Errorf("Conta deveria ir mascarada no alerta, obteve %q", user)
+	}
+}
+
+func TestLargeWithdrawalThresholdFromEnv(t *testing.T) {
+	t.Setenv("LARGE_WITHDRAWAL_THRESHOLD", "")
+	if got, _ := largeWithdrawalThresholdFromEnv(); !got.Equal(decimal.NewFromInt(defaultLargeWithdrawal)) {
+		t.Errorf("Esperava padrão %d, obteve %s", defaultLargeWithdrawal, got)
+	}
+
+	t.Setenv("LARGE_WITHDRAWAL_THRESHOLD", "2500.50")
+	if got, _ := largeWithdrawalThresholdFromEnv(); got.String() != "2500.5" {
+		t.Errorf("Esperava 2500.5, obteve %s", got)
+	}
+
+	t.Setenv("LARGE_WITHDRAWAL_THRESHOLD", "-1")
+	if _, err := largeWithdrawalThresholdFromEnv(); err == nil {
+		t.Error("Esperava erro para limite negativo")
+	}
+}
@@ -5,12 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"os"
-	"os/exec"
-	"sync"
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/lib/pq"
 )
 
 // =========================================================
@@ -18,7 +17,7 @@ import (
 // =========================================================
 func resetDBSingleton() {
 	db = nil
-	once = sync.Once{}
+	connectDB = initDB
 }
 
 func init() {
@@ -26,9 +25,9 @@ func init() {
 }
 
 // =========================================================
-// 🧱 Teste de verificação da tabela (ensureTableExists)
+// 📬 Teste do handler de mensagens (Lambda handler)
 // =========================================================
-func TestEnsureTableExists_TableAlreadyExists(t *testing.T) {
+func TestHandler_ProcessaMensagemValida(t *testing.T) {
 	resetDBSingleton()
 	dbMock, mock, err := sqlmock.New()
 	if err != nil {
@@ -37,148 +36,222 @@ func TestEnsureTableExists_TableAlreadyExists(t *testing.T) {
 	defer dbMock.Close()
 	db = dbMock
 
-	mock.ExpectQuery(`SELECT EXISTS`).
-		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	expectLedgerPosting(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "100", "-100")
 
-	ensureTableExists()
+	snsBody := map[string]interface{}{
+		"Message": `{"user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"100.00","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`,
+	}
+	bodyBytes, _ := json.Marshal(snsBody)
+
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{{Body: string(bodyBytes)}},
+	}
+
+	resp, err := handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Handler retornou erro: %v", err)
+	}
+	if len(resp.BatchItemFailures) != 0 {
+		t.Errorf("Não esperava falhas no lote, obteve %v", resp.BatchItemFailures)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Expectativas não atendidas: %v", err)
 	}
 }
 
-func TestEnsureTableExists_CreateTableWhenMissing(t *testing.T) {
+func TestHandler_MensagemInvalida(t *testing.T) {
 	resetDBSingleton()
-	dbMock, mock, err := sqlmock.New()
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{{MessageId: "msg-1", Body: "mensagem inválida"}},
+	}
+	resp, err := handler(context.Background(), event)
 	if err != nil {
-		t.Fatalf("Erro ao criar mock: %v", err)
+		t.Fatalf("Handler deveria lidar com erros, mas retornou: %v", err)
+	}
+	if len(resp.BatchItemFailures) != 1 || resp.BatchItemFailures[0].ItemIdentifier != "msg-1" {
+		t.Errorf("Esperava msg-1 em BatchItemFailures, obteve %v", resp.BatchItemFailures)
 	}
+}
+
+func TestHandler_InsertFails(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
 	defer dbMock.Close()
 	db = dbMock
 
-	mock.ExpectQuery(`SELECT EXISTS`).
-		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO transactions`).WillReturnError(errors.New("insert failed"))
+	mock.ExpectRollback()
 
-	mock.ExpectExec(`(?s)CREATE EXTENSION IF NOT EXISTS "uuid-ossp";.*CREATE TABLE public.transactions`).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	snsBody := map[string]interface{}{
+		"Message": `{"user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"100.00","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`,
+	}
+	bodyBytes, _ := json.Marshal(snsBody)
 
-	ensureTableExists()
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{{MessageId: "msg-1", Body: string(bodyBytes)}},
+	}
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("Expectativas não atendidas: %v", err)
+	resp, err := handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Handler não deveria retornar erro, mas retornou: %v", err)
+	}
+	if len(resp.BatchItemFailures) != 1 || resp.BatchItemFailures[0].ItemIdentifier != "msg-1" {
+		t.Errorf("Esperava msg-1 em BatchItemFailures, obteve %v", resp.BatchItemFailures)
 	}
 }
 
-// =========================================================
-// 📬 Teste do handler de mensagens (Lambda handler)
-// =========================================================
-func TestHandler_ProcessaMensagemValida(t *testing.T) {
+func TestHandler_LoteParcial(t *testing.T) {
 	resetDBSingleton()
-	dbMock, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Erro ao criar mock: %v", err)
-	}
+	dbMock, mock, _ := sqlmock.New()
 	defer dbMock.Close()
 	db = dbMock
 
-	mock.ExpectExec(`INSERT INTO transactions`).
-		WithArgs("user-123", sqlmock.AnyArg(), "deposit", sqlmock.AnyArg()).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectLedgerPosting(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "100", "-100")
+	mock.ExpectBegin().WillReturnError(errors.New("connection reset"))
 
 	snsBody := map[string]interface{}{
-		"Message": `{"user_id":"user-123","amount":"100.00","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`,
+		"Message": `{"user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"100.00","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`,
 	}
 	bodyBytes, _ := json.Marshal(snsBody)
 
 	event := events.SQSEvent{
-		Records: []events.SQSMessage{{Body: string(bodyBytes)}},
+		Records: []events.SQSMessage{
+			{MessageId: "ok", Body: string(bodyBytes)},
+			{MessageId: "veneno", Body: "mensagem inválida"},
+			{MessageId: "retry", Body: string(bodyBytes)},
+		},
 	}
 
-	err = handler(context.Background(), event)
+	resp, err := handler(context.Background(), event)
 	if err != nil {
-		t.Fatalf("Handler retornou erro: %v", err)
+		t.Fatalf("Handler não deveria retornar erro, mas retornou: %v", err)
 	}
 
+	var ids []string
+	for _, f := range resp.BatchItemFailures {
+		ids = append(ids, f.ItemIdentifier)
+	}
+	if len(ids) != 2 || ids[0] != "veneno" || ids[1] != "retry" {
+		t.Errorf("Esperava [veneno retry] em BatchItemFailures, obteve %v", ids)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Expectativas não atendidas: %v", err)
 	}
 }
 
-func TestHandler_MensagemInvalida(t *testing.T) {
+func TestHandler_SaqueRejeitadoNaoEhFalha(t *testing.T) {
 	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	expectWithdrawLock(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "0")
+	mock.ExpectExec(`UPDATE transactions SET status`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	snsBody := map[string]interface{}{
+		"Message": `{"user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"50.00","type":"withdraw","timestamp":"2025-11-07T00:00:00Z"}`,
+	}
+	bodyBytes, _ := json.Marshal(snsBody)
+
 	event := events.SQSEvent{
-		Records: []events.SQSMessage{{Body: "mensagem inválida"}},
+		Records: []events.SQSMessage{{MessageId: "saque", Body: string(bodyBytes)}},
 	}
-	err := handler(context.Background(), event)
+
+	resp, err := handler(context.Background(), event)
 	if err != nil {
-		t.Fatalf("Handler deveria lidar com erros, mas retornou: %v", err)
+		t.Fatalf("Handler não deveria retornar erro, mas retornou: %v", err)
+	}
+	if len(resp.BatchItemFailures) != 0 {
+		t.Errorf("Saque rejeitado não deveria voltar para a fila, obteve %v", resp.BatchItemFailures)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
 	}
 }
 
-func TestHandler_InsertFails(t *testing.T) {
+func TestHandler_FalhaPermanenteRegistraStatus(t *testing.T) {
 	resetDBSingleton()
 	dbMock, mock, _ := sqlmock.New()
 	defer dbMock.Close()
 	db = dbMock
 
-	mock.ExpectExec(`INSERT INTO transactions`).WillReturnError(errors.New("insert failed"))
+	const eventID = "0b6f5e1c-3a8e-4c55-9f0e-8f1f4a2b7c11"
+	mock.ExpectExec(`INSERT INTO transaction_failures`).
+		WithArgs(eventID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	snsBody := map[string]interface{}{
-		"Message": `{"user_id":"user-123","amount":"100.00","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`,
+		"Message": `{"event_id":"` + eventID + `","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"10","type":"transfer","timestamp":"2025-11-07T00:00:00Z"}`,
 	}
 	bodyBytes, _ := json.Marshal(snsBody)
 
 	event := events.SQSEvent{
-		Records: []events.SQSMessage{{Body: string(bodyBytes)}},
+		Records: []events.SQSMessage{{MessageId: "veneno", Body: string(bodyBytes)}},
 	}
 
-	err := handler(context.Background(), event)
-	if err != nil {
-		t.Fatalf("Handler não deveria retornar erro, mas retornou: %v", err)
+	resp, _ := handler(context.Background(), event)
+	if len(resp.BatchItemFailures) != 1 {
+		t.Errorf("Esperava a mensagem em BatchItemFailures, obteve %v", resp.BatchItemFailures)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
 	}
 }
 
-// =========================================================
-// ⚠️ Testes de falha na verificação/criação da tabela
-// =========================================================
-func TestEnsureTableExists_CheckQueryFails(t *testing.T) {
-	if os.Getenv("BE_CRASHER") == "1" {
-		dbMock, mock, _ := sqlmock.New()
-		defer dbMock.Close()
-		db = dbMock
-		mock.ExpectQuery(`SELECT EXISTS`).WillReturnError(errors.New("db error"))
-		ensureTableExists()
-		return
-	}
-
-	cmd := exec.Command(os.Args[0], "-test.run=TestEnsureTableExists_CheckQueryFails")
-	cmd.Env = append(os.Environ(), "BE_CRASHER=1")
-	err := cmd.Run()
-	if e, ok := err.(*exec.ExitError); ok && !e.Success() {
-		return
-	}
-	t.Fatalf("esperava que ensureTableExists chamasse os.Exit(1) ao falhar, mas não chamou")
+func TestClassifyDBError(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{"uuid inválido", &pq.Error{Code: "22P02"}, true},
+		{"violação de constraint", &pq.Error{Code: "23502"}, true},
+		{"conexão recusada", &pq.Error{Code: "08006"}, false},
+		{"erro genérico", errors.New("timeout"), false},
+	}
+
+	for _, c := range cases {
+		if got := isPermanent(classifyDBError(c.err)); got != c.permanent {
+			t.Errorf("%s: esperava permanente=%v, obteve %v", c.name, c.permanent, got)
+		}
+	}
 }
 
-func TestEnsureTableExists_CreateTableFails(t *testing.T) {
-	if os.Getenv("BE_CRASHER_CREATE") == "1" {
-		dbMock, mock, _ := sqlmock.New()
-		defer dbMock.Close()
-		db = dbMock
-		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
-		mock.ExpectExec(`(?s)CREATE EXTENSION IF NOT EXISTS "uuid-ossp";.*CREATE TABLE public.transactions`).
-			WillReturnError(errors.New("create failed"))
-		ensureTableExists()
-		return
-	}
-
-	cmd := exec.Command(os.Args[0], "-test.run=TestEnsureTableExists_CreateTableFails")
-	cmd.Env = append(os.Environ(), "BE_CRASHER_CREATE=1")
-	err := cmd.Run()
-	if e, ok := err.(*exec.ExitError); ok && !e.Success() {
-		return
-	}
-	t.Fatalf("esperava que ensureTableExists chamasse os.Exit(1) ao falhar ao criar tabela, mas não chamou")
+func TestHandler_DuplicadaTratadaComoSucesso(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	const eventID = "0b6f5e1c-3a8e-4c55-9f0e-8f1f4a2b7c11"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO transactions .* ON CONFLICT \(event_id\) DO NOTHING`).
+		WithArgs(eventID, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "100", "deposit", sqlmock.AnyArg(), statusPersisted, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	snsBody := map[string]interface{}{
+		"Message": `{"event_id":"` + eventID + `","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"100.00","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`,
+	}
+	bodyBytes, _ := json.Marshal(snsBody)
+
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{{MessageId: "dup", Body: string(bodyBytes)}},
+	}
+
+	resp, err := handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Handler não deveria retornar erro, mas retornou: %v", err)
+	}
+	if len(resp.BatchItemFailures) != 0 {
+		t.Errorf("Duplicata deveria ser tratada como sucesso, obteve %v", resp.BatchItemFailures)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
 }
 
 // =========================================================
@@ -187,7 +260,7 @@ func TestEnsureTableExists_CreateTableFails(t *testing.T) {
 func TestHandler_SQSEventVazio(t *testing.T) {
 	resetDBSingleton()
 	event := events.SQSEvent{Records: []events.SQSMessage{}}
-	err := handler(context.Background(), event)
+	_, err := handler(context.Background(), event)
 	if err != nil {
 		t.Fatalf("Handler não deve falhar com evento vazio: %v", err)
 	}
@@ -200,7 +273,7 @@ func TestHandler_SNSSemMessage(t *testing.T) {
 	event := events.SQSEvent{
 		Records: []events.SQSMessage{{Body: string(bodyBytes)}},
 	}
-	err := handler(context.Background(), event)
+	_, err := handler(context.Background(), event)
 	if err != nil {
 		t.Fatalf("Handler não deve falhar com SNS sem campo Message: %v", err)
 	}
@@ -213,7 +286,7 @@ func TestHandler_MessageCorrompido(t *testing.T) {
 	event := events.SQSEvent{
 		Records: []events.SQSMessage{{Body: string(bodyBytes)}},
 	}
-	err := handler(context.Background(), event)
+	_, err := handler(context.Background(), event)
 	if err != nil {
 		t.Fatalf("Handler não deve falhar com JSON corrompido: %v", err)
 	}
@@ -236,8 +309,8 @@ func TestGetDB_MockConnection(t *testing.T) {
 	dbMock, _, _ := sqlmock.New()
 	db = dbMock // força o mock antes da inicialização
 
-	result := getDB()
-	if result == nil {
-		t.Fatalf("getDB retornou nil — deveria retornar mock")
+	result, err := getDB(context.Background())
+	if err != nil || result == nil {
+		t.Fatalf("getDB deveria retornar o mock, obteve %v / %v", result, err)
 	}
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// =========================================================
+// 📜 Contrato — o consumer lê o JSON de referência do módulo
+// finorbit/events, o mesmo que o producer precisa gerar
+// =========================================================
+const contractFixture = "../events/testdata/transaction_event_v1.json"
+
+func TestContrato_ConsumerGravaEventoV1(t *testing.T) {
+	golden, err := os.ReadFile(contractFixture)
+	if err != nil {
+		t.Fatalf("Erro ao ler contrato: %v", err)
+	}
+
+	resetDBSingleton()
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar mock: %v", err)
+	}
+	defer dbMock.Close()
+
+	expectLedgerPosting(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "150.5", "-150.5")
+
+	body, err := snsEnvelope(strings.TrimSpace(string(golden)), nil)
+	if err != nil {
+		t.Fatalf("Erro ao montar envelope: %v", err)
+	}
+	if err := processRecord(context.Background(), dbMock, events.SQSMessage{Body: body}); err != nil {
+		t.Fatalf("Consumer recusou o evento do contrato: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestContrato_VersaoFuturaEhPermanente(t *testing.T) {
+	body, _ := snsEnvelope(`{"schema_version":99,"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11"}`, nil)
+
+	err := processRecord(context.Background(), nil, events.SQSMessage{Body: body})
+	if !isPermanent(err) {
+		t.Errorf("Esperava falha permanente para versão desconhecida, obteve %v", err)
+	}
+}
+
+func TestContrato_ConsumerGravaCloudEventV1(t *testing.T) {
+	golden, err := os.ReadFile("../events/testdata/transaction_cloudevent_v1.json")
+	if err != nil {
+		t.Fatalf("Erro ao ler contrato: %v", err)
+	}
+
+	resetDBSingleton()
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar mock: %v", err)
+	}
+	defer dbMock.Close()
+
+	expectLedgerPosting(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "150.5", "-150.5")
+
+	body, _ := snsEnvelope(strings.TrimSpace(string(golden)), nil)
+	if err := processRecord(context.Background(), dbMock, events.SQSMessage{Body: body}); err != nil {
+		t.Fatalf("Consumer recusou o CloudEvent do contrato: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
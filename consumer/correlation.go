@@ -0,0 +1,29 @@
+package main
+
+import "finorbit/platform/logging"
+
+// =========================================================
+// 🔗 Correlation ID
+// =========================================================
+// O producer envia o correlation_id como atributo da mensagem (X-Correlation-ID
+// do cliente ou o RequestID do API Gateway). O consumer o anexa a cada linha
+// de log e o grava na linha de `transactions`, ligando requisição, logs e banco.
+//
+// Atributos da mensagem não entram na assinatura do SNS (stringToSign cobre
+// só Message, MessageId, Subject, Timestamp, TopicArn e Type): mesmo com
+// SNS_VERIFY_SIGNATURES, quem reenviar um envelope legítimo pode trocar o
+// correlation_id e o traceparent. Os dois são pistas para achar logs e
+// traces, nunca identidade nem critério de decisão — o identificador
+// confiável é o event_id, que vem no corpo assinado e acompanha os logs do
+// processamento. Por isso o valor recebido passa pela mesma validação do
+// producer e é descartado se não a cumprir.
+const correlationAttribute = "correlation_id"
+
+// correlationIDFromAttributes devolve o correlation_id dos atributos, ou
+// vazio se ele estiver ausente, longo demais ou fora do ASCII visível.
+func correlationIDFromAttributes(attributes map[string]string) string {
+	if id := attributes[correlationAttribute]; logging.ValidCorrelationID(id) {
+		return id
+	}
+	return ""
+}
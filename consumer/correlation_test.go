@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// =========================================================
+// 🔗 Correlation ID
+// =========================================================
+func TestProcessRecord_GravaCorrelationIDDoEnvelope(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar mock: %v", err)
+	}
+	defer dbMock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO transactions`).
+		WithArgs(sqlmock.AnyArg(), "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "10", "deposit", sqlmock.AnyArg(), statusPersisted, "req-abc").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	logs := useLogBuffer(t, slog.LevelInfo)
+
+	body, _ := snsEnvelope(rawEvent, map[string]string{"type": "deposit", correlationAttribute: "req-abc"})
+	_ = processRecord(context.Background(), dbMock, events.SQSMessage{MessageId: "m-1", Body: body})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("correlation_id não chegou ao INSERT: %v", err)
+	}
+	for _, line := range logLines(t, logs) {
+		if line[logKeyCorrelationID] != "req-abc" {
+			t.Errorf("Esperava o correlation_id em todas as linhas, obteve %v", line)
+		}
+	}
+}
+
+func TestCorrelationIDFromAttributes_DescartaValoresInvalidos(t *testing.T) {
+	cases := map[string]string{
+		"req-abc":                "req-abc",
+		"com espaço":             "",
+		"quebra\nde linha":       "",
+		strings.Repeat("a", 129): "",
+		strings.Repeat("a", 128): strings.Repeat("a", 128),
+	}
+	for value, want := range cases {
+		if got := correlationIDFromAttributes(map[string]string{correlationAttribute: value}); got != want {
+			t.Errorf("Para %q esperava %q, obteve %q", value, want, got)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// useFlakyDB faz as primeiras `failures` conexões falharem; depois devolve
+// um banco mockado. Retorna o contador de tentativas.
+func useFlakyDB(t *testing.T, failures int) *int {
+	t.Helper()
+	resetDBSingleton()
+	savedDelay := dbConnectBaseDelay
+	dbConnectBaseDelay = time.Millisecond
+
+	dbMock, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar mock: %v", err)
+	}
+
+	calls := 0
+	connectDB = func(context.Context) (*sql.DB, error) {
+		calls++
+		if calls <= failures {
+			return nil, errors.New("connection refused")
+		}
+		return dbMock, nil
+	}
+	t.Cleanup(func() {
+		dbConnectBaseDelay = savedDelay
+		resetDBSingleton()
+		dbMock.Close()
+	})
+	return &calls
+}
+
+// =========================================================
+// 🔁 Conexão com retentativas
+// =========================================================
+func TestGetDB_RetentaAteConectar(t *testing.T) {
+	calls := useFlakyDB(t, 2)
+
+	d, err := getDB(context.Background())
+	if err != nil || d == nil {
+		t.Fatalf("Esperava conexão após retentativas, obteve %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("Esperava 3 tentativas, obteve %d", *calls)
+	}
+
+	// Conexão aberta é reaproveitada
+	getDB(context.Background())
+	if *calls != 3 {
+		t.Errorf("Conexão deveria ser reaproveitada, houve %d tentativas", *calls)
+	}
+}
+
+func TestGetDB_FalhaPermiteNovaInicializacao(t *testing.T) {
+	calls := useFlakyDB(t, dbConnectAttempts)
+
+	if _, err := getDB(context.Background()); err == nil {
+		t.Fatal("Esperava erro após esgotar as tentativas")
+	}
+	if *calls != dbConnectAttempts {
+		t.Errorf("Esperava %d tentativas, obteve %d", dbConnectAttempts, *calls)
+	}
+
+	// A invocação seguinte tenta de novo em vez de ficar presa na falha
+	if d, err := getDB(context.Background()); err != nil || d == nil {
+		t.Errorf("Esperava conectar na invocação seguinte, obteve %v", err)
+	}
+}
+
+func TestGetDB_RespeitaPrazoDaInvocacao(t *testing.T) {
+	calls := useFlakyDB(t, dbConnectAttempts)
+	dbConnectBaseDelay = time.Second
+
+	// Prazo curto: não sobra tempo para esperar o backoff com a margem
+	ctx, cancel := context.WithTimeout(context.Background(), dbDeadlineMargin+500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := getDB(ctx); err == nil {
+		t.Fatal("Esperava erro sem prazo para novas tentativas")
+	}
+	if *calls != 1 || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Não deveria esperar além do prazo: %d tentativas em %v", *calls, time.Since(start))
+	}
+}
+
+func TestGetDB_ModoTesteNaoRetenta(t *testing.T) {
+	resetDBSingleton()
+	t.Setenv("GO_ENV", "test")
+
+	if _, err := getDB(context.Background()); !errors.Is(err, errDBDisabled) {
+		t.Errorf("Esperava errDBDisabled, obteve %v", err)
+	}
+}
+
+func TestDBConnectBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  dbConnectBaseDelay,
+		3:  4 * dbConnectBaseDelay,
+		10: dbConnectMaxDelay,
+		70: dbConnectMaxDelay,
+	}
+	for attempt, want := range cases {
+		if got := dbConnectBackoff(attempt); got != want {
+			t.Errorf("dbConnectBackoff(%d) = %v, esperava %v", attempt, got, want)
+		}
+	}
+}
+
+// =========================================================
+// 📬 Banco indisponível vira falha do lote
+// =========================================================
+func TestHandler_BancoIndisponivelDevolveLote(t *testing.T) {
+	useRecordingAlerter(t)
+	useFlakyDB(t, 2*dbConnectAttempts)
+
+	body, _ := snsEnvelope(rawEvent, nil)
+	resp, err := handler(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "a", Body: body},
+		{MessageId: "b", Body: body},
+	}})
+	if err != nil {
+		t.Fatalf("Handler não deveria retornar erro, obteve %v", err)
+	}
+	if len(resp.BatchItemFailures) != 2 {
+		t.Errorf("Esperava o lote inteiro em BatchItemFailures, obteve %v", resp.BatchItemFailures)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/shopspring/decimal"
+
+	txevents "finorbit/events"
+	"finorbit/platform/logging"
+)
+
+// =========================================================
+// 📒 Ledger de partidas dobradas
+// =========================================================
+//
+// Cada Transaction gera um lançamento (journal_entries) com postings que
+// somam zero: o valor entra/sai da conta do usuário e a contrapartida vai
+// para a conta externa. O saldo de qualquer conta é a soma das postings.
+
+// externalAccountID é a conta de sistema que representa o dinheiro fora da
+// plataforma, criada pela migração 0003_create_ledger. Ela entra em todo
+// lançamento, então seu saldo não é materializado em accounts.balance —
+// atualizar essa linha serializaria todos os consumers num único lock. O
+// saldo dela é sempre a soma das suas postings.
+const externalAccountID = "00000000-0000-0000-0000-000000000001"
+
+// errDuplicateEvent sinaliza que o event_id já foi gravado anteriormente.
+var errDuplicateEvent = errors.New("evento já processado")
+
+// errInsufficientFunds sinaliza que o saque foi gravado como rejeitado por
+// falta de saldo. É um desfecho de negócio, não uma falha de processamento.
+var errInsufficientFunds = errors.New("saldo insuficiente")
+
+const (
+	statusPersisted = "persisted"
+	statusRejected  = "rejected"
+)
+
+type posting struct {
+	accountID string
+	amount    decimal.Decimal
+}
+
+// journalPostings traduz a transação nas postings do lançamento.
+func journalPostings(tx Transaction) ([]posting, error) {
+	if !tx.Amount.IsPositive() {
+		return nil, fmt.Errorf("valor da transação deve ser positivo: %s", tx.Amount.String())
+	}
+
+	switch tx.Type {
+	case txevents.TypeDeposit:
+		return []posting{
+			{accountID: tx.UserID, amount: tx.Amount},
+			{accountID: externalAccountID, amount: tx.Amount.Neg()},
+		}, nil
+	case txevents.TypeWithdraw:
+		return []posting{
+			{accountID: tx.UserID, amount: tx.Amount.Neg()},
+			{accountID: externalAccountID, amount: tx.Amount},
+		}, nil
+	default:
+		return nil, fmt.Errorf("tipo de transação desconhecido: %q", tx.Type)
+	}
+}
+
+// persistTransaction grava a transação, o lançamento e as postings, e
+// atualiza o saldo da conta do usuário — tudo em uma única transação do banco.
+func persistTransaction(ctx context.Context, d *sql.DB, tx Transaction) error {
+	postings, err := journalPostings(tx)
+	if err != nil {
+		return permanent(err)
+	}
+
+	dbTx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return dbRetryable(fmt.Errorf("erro ao iniciar transação: %w", err))
+	}
+	defer dbTx.Rollback()
+
+	// Eventos sem event_id (legados) gravam NULL, que não conflita no índice único
+	var eventID sql.NullString
+	if tx.EventID != "" {
+		eventID = sql.NullString{String: tx.EventID, Valid: true}
+	}
+	var correlationID sql.NullString
+	if id := logging.CorrelationIDFromContext(ctx); id != "" {
+		correlationID = sql.NullString{String: id, Valid: true}
+	}
+
+	var transactionID string
+	err = dbTx.QueryRowContext(ctx,
+		`INSERT INTO transactions (event_id, user_id, amount, type, timestamp, status, correlation_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (event_id) DO NOTHING
+		 RETURNING id`,
+		eventID, tx.UserID, tx.Amount.String(), tx.Type, tx.Timestamp, statusPersisted, correlationID,
+	).Scan(&transactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errDuplicateEvent
+	}
+	if err != nil {
+		return classifyDBError(fmt.Errorf("erro ao salvar transação no banco: %w", err))
+	}
+
+	if _, err := dbTx.ExecContext(ctx,
+		`INSERT INTO accounts (id) VALUES ($1) ON CONFLICT (id) DO NOTHING`,
+		tx.UserID,
+	); err != nil {
+		return classifyDBError(fmt.Errorf("erro ao abrir conta: %w", err))
+	}
+
+	if tx.Type == txevents.TypeWithdraw {
+		// Bloqueia a linha da conta até o commit: saques concorrentes
+		// (inclusive de outro consumer) esperam aqui e enxergam o saldo
+		// já debitado, impedindo gasto duplo.
+		var raw string
+		if err := dbTx.QueryRowContext(ctx,
+			`SELECT balance FROM accounts WHERE id = $1 FOR UPDATE`,
+			tx.UserID,
+		).Scan(&raw); err != nil {
+			return classifyDBError(fmt.Errorf("erro ao bloquear conta: %w", err))
+		}
+
+		balance, err := decimal.NewFromString(raw)
+		if err != nil {
+			return retryable(fmt.Errorf("saldo inválido na conta %s: %w", tx.UserID, err))
+		}
+
+		if balance.LessThan(tx.Amount) {
+			reason := fmt.Sprintf("saldo insuficiente: disponível %s, solicitado %s", balance.StringFixed(2), tx.Amount.StringFixed(2))
+			if _, err := dbTx.ExecContext(ctx,
+				`UPDATE transactions SET status = $2, rejection_reason = $3 WHERE id = $1`,
+				transactionID, statusRejected, reason,
+			); err != nil {
+				return classifyDBError(fmt.Errorf("erro ao rejeitar transação: %w", err))
+			}
+			if err := dbTx.Commit(); err != nil {
+				return classifyDBError(fmt.Errorf("erro ao confirmar rejeição: %w", err))
+			}
+			return errInsufficientFunds
+		}
+	}
+
+	var journalID string
+	if err := dbTx.QueryRowContext(ctx,
+		`INSERT INTO journal_entries (transaction_id) VALUES ($1) RETURNING id`,
+		transactionID,
+	).Scan(&journalID); err != nil {
+		return classifyDBError(fmt.Errorf("erro ao criar lançamento: %w", err))
+	}
+
+	for _, p := range postings {
+		// O saldo do usuário é atualizado antes da posting: o UPDATE segura a
+		// linha da conta até o commit, então as postings da conta saem em
+		// ordem de id com o saldo resultante de cada uma (balance_after), que
+		// o extrato lê sem somar o histórico.
+		var balanceAfter sql.NullString
+		if p.accountID != externalAccountID {
+			if err := dbTx.QueryRowContext(ctx,
+				`UPDATE accounts SET balance = balance + $2, updated_at = now() WHERE id = $1 RETURNING balance`,
+				p.accountID, p.amount.String(),
+			).Scan(&balanceAfter); err != nil {
+				return classifyDBError(fmt.Errorf("erro ao atualizar saldo: %w", err))
+			}
+		}
+
+		if _, err := dbTx.ExecContext(ctx,
+			`INSERT INTO postings (journal_entry_id, account_id, amount, balance_after) VALUES ($1, $2, $3, $4)`,
+			journalID, p.accountID, p.amount.String(), balanceAfter,
+		); err != nil {
+			return classifyDBError(fmt.Errorf("erro ao registrar posting: %w", err))
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return classifyDBError(fmt.Errorf("erro ao confirmar transação: %w", err))
+	}
+	return nil
+}
+
+// recordFailure registra (best effort) que o evento falhou de forma
+// permanente, para que a consulta de status responda "failed". Roda fora
+// da transação do lançamento, que já foi desfeita.
+func recordFailure(ctx context.Context, d *sql.DB, eventID string, cause error) {
+	_, err := d.ExecContext(ctx,
+		`INSERT INTO transaction_failures (event_id, reason) VALUES ($1, $2)
+		 ON CONFLICT (event_id) DO UPDATE
+		 SET reason = EXCLUDED.reason,
+		     attempts = transaction_failures.attempts + 1,
+		     last_failed_at = now()`,
+		eventID, cause.Error(),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Erro ao registrar falha do evento", logKeyEventID, eventID, logKeyError, err)
+	}
+}
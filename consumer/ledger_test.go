@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+)
+
+// expectLedgerPosting registra no mock a sequência completa de um
+// lançamento bem-sucedido: transação, conta, journal e duas postings. Só o
+// saldo do usuário é atualizado (e gravado na posting) — o da conta de
+// sistema vem das postings.
+func expectLedgerPosting(mock sqlmock.Sqlmock, userID, userAmount, externalAmount string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO transactions`).
+		WithArgs(sqlmock.AnyArg(), userID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), statusPersisted, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("tx-1"))
+	mock.ExpectExec(`INSERT INTO accounts`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO journal_entries`).WithArgs("tx-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("je-1"))
+	mock.ExpectQuery(`UPDATE accounts SET balance`).WithArgs(userID, userAmount).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(userAmount))
+	mock.ExpectExec(`INSERT INTO postings`).WithArgs("je-1", userID, userAmount, userAmount).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO postings`).WithArgs("je-1", externalAccountID, externalAmount, nil).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+}
+
+// =========================================================
+// 📒 Postings do lançamento
+// =========================================================
+func TestJournalPostings_SomamZero(t *testing.T) {
+	for _, txType := range []string{"deposit", "withdraw"} {
+		tx := Transaction{UserID: "user-1", Amount: decimal.RequireFromString("42.50"), Type: txType}
+
+		postings, err := journalPostings(tx)
+		if err != nil {
+			t.Fatalf("%s: erro inesperado: %v", txType, err)
+		}
+
+		total := decimal.Zero
+		for _, p := range postings {
+			total = total.Add(p.amount)
+		}
+		if !total.IsZero() {
+			t.Errorf("%s: postings deveriam somar zero, somaram %s", txType, total)
+		}
+	}
+}
+
+func TestJournalPostings_SinalPorTipo(t *testing.T) {
+	amount := decimal.RequireFromString("10")
+
+	deposit, _ := journalPostings(Transaction{UserID: "user-1", Amount: amount, Type: "deposit"})
+	if !deposit[0].amount.Equal(amount) || deposit[0].accountID != "user-1" {
+		t.Errorf("Depósito deveria creditar a conta do usuário, obteve %+v", deposit[0])
+	}
+
+	withdraw, _ := journalPostings(Transaction{UserID: "user-1", Amount: amount, Type: "withdraw"})
+	if !withdraw[0].amount.Equal(amount.Neg()) || withdraw[0].accountID != "user-1" {
+		t.Errorf("Saque deveria debitar a conta do usuário, obteve %+v", withdraw[0])
+	}
+}
+
+func TestJournalPostings_Invalidas(t *testing.T) {
+	cases := []Transaction{
+		{UserID: "user-1", Amount: decimal.RequireFromString("10"), Type: "transfer"},
+		{UserID: "user-1", Amount: decimal.Zero, Type: "deposit"},
+		{UserID: "user-1", Amount: decimal.RequireFromString("-5"), Type: "withdraw"},
+	}
+
+	for _, tx := range cases {
+		if _, err := journalPostings(tx); err == nil {
+			t.Errorf("Esperava erro para %+v", tx)
+		}
+	}
+}
+
+// =========================================================
+// 💾 Persistência atômica
+// =========================================================
+func TestPersistTransaction_Deposito(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	expectLedgerPosting(mock, "user-1", "25.1", "-25.1")
+
+	tx := Transaction{UserID: "user-1", Amount: decimal.RequireFromString("25.10"), Type: "deposit"}
+	if err := persistTransaction(context.Background(), dbMock, tx); err != nil {
+		t.Fatalf("persistTransaction retornou erro: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestPersistTransaction_FalhaNaPostingFazRollback(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO transactions`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("tx-1"))
+	mock.ExpectExec(`INSERT INTO accounts`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO journal_entries`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("je-1"))
+	mock.ExpectQuery(`UPDATE accounts SET balance`).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("5.00"))
+	mock.ExpectExec(`INSERT INTO postings`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	tx := Transaction{UserID: "user-1", Amount: decimal.RequireFromString("5"), Type: "deposit"}
+	err := persistTransaction(context.Background(), dbMock, tx)
+	if err == nil || isPermanent(err) {
+		t.Fatalf("Esperava erro temporário, obteve %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+// =========================================================
+// 🔒 Saques com bloqueio da conta
+// =========================================================
+func expectWithdrawLock(mock sqlmock.Sqlmock, userID, balance string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO transactions`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("tx-1"))
+	mock.ExpectExec(`INSERT INTO accounts`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT balance FROM accounts WHERE id = \$1 FOR UPDATE`).WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(balance))
+}
+
+func TestPersistTransaction_SaqueComSaldo(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	expectWithdrawLock(mock, "user-1", "30.00")
+	mock.ExpectQuery(`INSERT INTO journal_entries`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("je-1"))
+	mock.ExpectQuery(`UPDATE accounts SET balance`).WithArgs("user-1", "-30").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	mock.ExpectExec(`INSERT INTO postings`).WithArgs("je-1", "user-1", "-30", "0.00").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO postings`).WithArgs("je-1", externalAccountID, "30", nil).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	tx := Transaction{UserID: "user-1", Amount: decimal.RequireFromString("30"), Type: "withdraw"}
+	if err := persistTransaction(context.Background(), dbMock, tx); err != nil {
+		t.Fatalf("Saque com saldo exato deveria ser lançado, obteve %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestPersistTransaction_SaqueSemSaldoEhRejeitado(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	expectWithdrawLock(mock, "user-1", "10.00")
+	mock.ExpectExec(`UPDATE transactions SET status`).
+		WithArgs("tx-1", statusRejected, "saldo insuficiente: disponível 10.00, solicitado 30.01").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx := Transaction{UserID: "user-1", Amount: decimal.RequireFromString("30.01"), Type: "withdraw"}
+	if err := persistTransaction(context.Background(), dbMock, tx); !errors.Is(err, errInsufficientFunds) {
+		t.Fatalf("Esperava errInsufficientFunds, obteve %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestPersistTransaction_TipoInvalidoEhPermanente(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	tx := Transaction{UserID: "user-1", Amount: decimal.RequireFromString("5"), Type: "transfer"}
+	if err := persistTransaction(context.Background(), dbMock, tx); !isPermanent(err) {
+		t.Fatalf("Esperava erro permanente, obteve %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Nenhuma consulta deveria ser feita: %v", err)
+	}
+}
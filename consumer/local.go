@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pborman/uuid"
+
+	txevents "finorbit/events"
+)
+
+// =========================================================
+// 🏠 Execução local — reproduz o SNS → SQS → Lambda sem AWS
+// =========================================================
+//
+// `bootstrap run [arquivo.jsonl|-]` lê um evento JSON por linha (o mesmo
+// formato que o producer publica, puro ou CloudEvents), embrulha cada um nos envelopes SNS e SQS
+// como a AWS faz e entrega ao handler em lotes. Registros devolvidos em
+// BatchItemFailures voltam para a fila até maxReceives, como no redrive,
+// e ficam invisíveis por um tempo que dobra a cada entrega — um banco fora
+// do ar não esgota as tentativas num piscar de olhos.
+
+const (
+	localTopicARN = "arn:aws:sns:local:000000000000:finorbit-transactions"
+	localQueueARN = "arn:aws:sqs:local:000000000000:finorbit-transactions"
+
+	// maxVisibilityDelay limita a espera entre entregas da mesma mensagem.
+	maxVisibilityDelay = time.Minute
+)
+
+// snsEnvelope monta a notificação SNS como ela chega ao corpo da mensagem
+// SQS (raw message delivery desligado).
+func snsEnvelope(message string, attributes map[string]string) (string, error) {
+	msgAttributes := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		msgAttributes[name] = map[string]string{"Type": "String", "Value": value}
+	}
+
+	body, err := json.Marshal(events.SNSEntity{
+		Type:              "Notification",
+		MessageID:         uuid.NewRandom().String(),
+		TopicArn:          localTopicARN,
+		Message:           message,
+		Timestamp:         time.Now().UTC(),
+		SignatureVersion:  "1",
+		MessageAttributes: msgAttributes,
+	})
+	return string(body), err
+}
+
+// eventAttributes replica os atributos que o producer envia ao SNS.
+func eventAttributes(message string) map[string]string {
+	payload, ce, err := eventPayload([]byte(message))
+	if err != nil {
+		return nil
+	}
+
+	var tx Transaction
+	if err := json.Unmarshal(payload, &tx); err != nil {
+		return nil
+	}
+	version, _ := txevents.Version(payload)
+
+	contentType := "application/json"
+	if ce != nil {
+		contentType = txevents.CloudEventsContentType
+	}
+	return map[string]string{
+		"type":           tx.Type,
+		"event_id":       tx.EventID,
+		"schema_version": strconv.Itoa(version),
+		"content-type":   contentType,
+	}
+}
+
+// =========================================================
+// 📥 Fila em memória
+// =========================================================
+type queuedMessage struct {
+	message   events.SQSMessage
+	receives  int
+	visibleAt time.Time
+}
+
+type localQueue struct {
+	mu       sync.Mutex
+	messages []*queuedMessage
+	dead     []events.SQSMessage
+
+	// visibility é a espera antes da 2ª entrega; dobra a cada nova falha.
+	visibility time.Duration
+}
+
+func newLocalQueue(visibility time.Duration) *localQueue {
+	return &localQueue{visibility: visibility}
+}
+
+func (q *localQueue) redeliveryDelay(receives int) time.Duration {
+	delay := q.visibility << (receives - 1)
+	if delay > maxVisibilityDelay || delay < 0 {
+		return maxVisibilityDelay
+	}
+	return delay
+}
+
+// Send publica um evento como o tópico SNS faria para a fila assinada.
+func (q *localQueue) Send(message string) error {
+	body, err := snsEnvelope(message, eventAttributes(message))
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.messages = append(q.messages, &queuedMessage{message: events.SQSMessage{
+		MessageId:      uuid.NewRandom().String(),
+		Body:           body,
+		EventSource:    "aws:sqs",
+		EventSourceARN: localQueueARN,
+		AWSRegion:      "local",
+	}})
+	return nil
+}
+
+// receive retira até max mensagens já visíveis, incrementando o contador
+// de entregas.
+func (q *localQueue) receive(max int) []*queuedMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	var batch, remaining []*queuedMessage
+	for _, m := range q.messages {
+		if len(batch) < max && !m.visibleAt.After(now) {
+			batch = append(batch, m)
+		} else {
+			remaining = append(remaining, m)
+		}
+	}
+	q.messages = remaining
+
+	for _, m := range batch {
+		m.receives++
+		m.message.Attributes = map[string]string{
+			"ApproximateReceiveCount":          strconv.Itoa(m.receives),
+			"SentTimestamp":                    strconv.FormatInt(time.Now().UnixMilli(), 10),
+			"ApproximateFirstReceiveTimestamp": strconv.FormatInt(time.Now().UnixMilli(), 10),
+		}
+	}
+	return batch
+}
+
+// requeue devolve a mensagem à fila, invisível até a próxima entrega.
+func (q *localQueue) requeue(m *queuedMessage) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	m.visibleAt = time.Now().Add(q.redeliveryDelay(m.receives))
+	q.messages = append(q.messages, m)
+}
+
+// nextVisible devolve quando a próxima mensagem invisível volta à fila.
+func (q *localQueue) nextVisible() time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var next time.Time
+	for _, m := range q.messages {
+		if next.IsZero() || m.visibleAt.Before(next) {
+			next = m.visibleAt
+		}
+	}
+	return next
+}
+
+func (q *localQueue) deadLetter(m *queuedMessage) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dead = append(q.dead, m.message)
+}
+
+func (q *localQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
+
+// DeadLetters devolve as mensagens que esgotaram as entregas.
+func (q *localQueue) DeadLetters() []events.SQSMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]events.SQSMessage(nil), q.dead...)
+}
+
+// =========================================================
+// 🔁 Drenagem da fila
+// =========================================================
+type drainResult struct {
+	Processed, Retried, DeadLettered int
+}
+
+type sqsHandler func(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error)
+
+// drainQueue entrega lotes ao handler até a fila esvaziar.
+func drainQueue(ctx context.Context, q *localQueue, h sqsHandler, batchSize, maxReceives int) (drainResult, error) {
+	var result drainResult
+
+	for q.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		batch := q.receive(batchSize)
+		if len(batch) == 0 {
+			// Só restam mensagens aguardando a reentrega
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(time.Until(q.nextVisible())):
+			}
+			continue
+		}
+
+		event := events.SQSEvent{Records: make([]events.SQSMessage, len(batch))}
+		for i, m := range batch {
+			event.Records[i] = m.message
+		}
+
+		resp, err := h(ctx, event)
+		if err != nil {
+			// Erro da função inteira: na AWS o lote todo volta para a fila
+			slog.WarnContext(ctx, "Handler retornou erro, lote será reentregue", logKeyError, err)
+			resp.BatchItemFailures = nil
+			for _, m := range batch {
+				resp.BatchItemFailures = append(resp.BatchItemFailures,
+					events.SQSBatchItemFailure{ItemIdentifier: m.message.MessageId})
+			}
+		}
+
+		failed := make(map[string]bool, len(resp.BatchItemFailures))
+		for _, f := range resp.BatchItemFailures {
+			failed[f.ItemIdentifier] = true
+		}
+
+		for _, m := range batch {
+			switch {
+			case !failed[m.message.MessageId]:
+				result.Processed++
+			case m.receives >= maxReceives:
+				slog.ErrorContext(ctx, "Mensagem enviada à DLQ local", "message_id", m.message.MessageId, "receives", m.receives)
+				q.deadLetter(m)
+				result.DeadLettered++
+			default:
+				q.requeue(m)
+				result.Retried++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// enqueueLines lê um evento por linha; linhas vazias e comentários (#) são
+// ignorados.
+func enqueueLines(r io.Reader, q *localQueue) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	count := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := q.Send(line); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// =========================================================
+// ▶️ Subcomando `run`
+// =========================================================
+func runLocalCommand(ctx context.Context, args []string, stdin io.Reader) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	batchSize := flags.Int("batch", 10, "mensagens por invocação do handler")
+	maxReceives := flags.Int("max-receives", 5, "entregas antes de mandar à DLQ local")
+	visibility := flags.Duration("visibility", time.Second, "espera antes de reentregar uma mensagem que falhou (dobra a cada entrega)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *batchSize <= 0 || *maxReceives <= 0 {
+		return errors.New("-batch e -max-receives devem ser positivos")
+	}
+	if *visibility < 0 {
+		return errors.New("-visibility não pode ser negativo")
+	}
+
+	source := stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("erro ao abrir %s: %w", path, err)
+		}
+		defer f.Close()
+		source = f
+	}
+
+	q := newLocalQueue(*visibility)
+	count, err := enqueueLines(source, q)
+	if err != nil {
+		return fmt.Errorf("erro ao ler eventos: %w", err)
+	}
+	slog.InfoContext(ctx, "Eventos enfileirados", "count", count)
+
+	result, err := drainQueue(ctx, q, handler, *batchSize, *maxReceives)
+	slog.InfoContext(ctx, "Execução local concluída",
+		"processed", result.Processed, "retried", result.Retried, "dead_lettered", result.DeadLettered)
+	return err
+}
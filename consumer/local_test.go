@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const localTestEvent = `{"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"100.00","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`
+
+// =========================================================
+// 📦 Envelopes SNS/SQS
+// =========================================================
+func TestLocalQueue_EnvelopeSNS(t *testing.T) {
+	q := newLocalQueue(time.Millisecond)
+	if err := q.Send(localTestEvent); err != nil {
+		t.Fatalf("Erro ao enfileirar: %v", err)
+	}
+
+	batch := q.receive(10)
+	if len(batch) != 1 {
+		t.Fatalf("Esperava 1 mensagem, obteve %d", len(batch))
+	}
+	msg := batch[0].message
+	if msg.EventSource != "aws:sqs" || msg.Attributes["ApproximateReceiveCount"] != "1" {
+		t.Errorf("Mensagem SQS incompleta: %+v", msg)
+	}
+
+	var envelope events.SNSEntity
+	if err := json.Unmarshal([]byte(msg.Body), &envelope); err != nil {
+		t.Fatalf("Corpo não é um envelope SNS: %v", err)
+	}
+	if envelope.Message != localTestEvent || envelope.Type != "Notification" {
+		t.Errorf("Envelope SNS incorreto: %+v", envelope)
+	}
+	attr, _ := envelope.MessageAttributes["type"].(map[string]interface{})
+	if attr["Value"] != "deposit" {
+		t.Errorf("Esperava atributo type=deposit, obteve %v", envelope.MessageAttributes)
+	}
+}
+
+func TestDrainQueue_ProcessaComHandlerReal(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar mock: %v", err)
+	}
+	defer dbMock.Close()
+	db = dbMock
+
+	expectLedgerPosting(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "100", "-100")
+
+	q := newLocalQueue(time.Millisecond)
+	if _, err := enqueueLines(strings.NewReader(localTestEvent+"\n"), q); err != nil {
+		t.Fatalf("Erro ao enfileirar: %v", err)
+	}
+
+	result, err := drainQueue(context.Background(), q, handler, 10, 3)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if result.Processed != 1 || result.DeadLettered != 0 {
+		t.Errorf("Resultado inesperado: %+v", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+// =========================================================
+// 🔁 Reentrega e DLQ local
+// =========================================================
+func TestDrainQueue_ReentregaAteDLQ(t *testing.T) {
+	q := newLocalQueue(time.Millisecond)
+	_, _ = enqueueLines(strings.NewReader("# comentário\n\n"+localTestEvent+"\n"+localTestEvent+"\n"), q)
+
+	calls := map[string]int{}
+	flaky := func(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+		var resp events.SQSEventResponse
+		for _, r := range event.Records {
+			calls[r.MessageId]++
+			resp.BatchItemFailures = append(resp.BatchItemFailures,
+				events.SQSBatchItemFailure{ItemIdentifier: r.MessageId})
+		}
+		return resp, nil
+	}
+
+	result, err := drainQueue(context.Background(), q, flaky, 1, 3)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if result.DeadLettered != 2 || result.Retried != 4 || result.Processed != 0 {
+		t.Errorf("Resultado inesperado: %+v", result)
+	}
+	for id, n := range calls {
+		if n != 3 {
+			t.Errorf("Mensagem %s entregue %d vezes, esperava 3", id, n)
+		}
+	}
+	if len(q.DeadLetters()) != 2 {
+		t.Errorf("Esperava 2 mensagens na DLQ local, obteve %d", len(q.DeadLetters()))
+	}
+}
+
+func TestDrainQueue_ReentregaEsperaVisibilidade(t *testing.T) {
+	q := newLocalQueue(20 * time.Millisecond)
+	_, _ = enqueueLines(strings.NewReader(localTestEvent+"\n"), q)
+
+	var deliveries []time.Time
+	failing := func(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+		deliveries = append(deliveries, time.Now())
+		return events.SQSEventResponse{}, errors.New("banco indisponível")
+	}
+
+	result, err := drainQueue(context.Background(), q, failing, 10, 3)
+	if err != nil || result.DeadLettered != 1 {
+		t.Fatalf("Esperava a mensagem na DLQ, obteve %+v (%v)", result, err)
+	}
+	// Espera 20ms antes da 2ª entrega e 40ms antes da 3ª
+	for i, want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond} {
+		if got := deliveries[i+1].Sub(deliveries[i]); got < want {
+			t.Errorf("Entrega %d veio após %s, esperava ao menos %s", i+2, got, want)
+		}
+	}
+}
+
+func TestRunLocalCommand_ArgumentosInvalidos(t *testing.T) {
+	if err := runLocalCommand(context.Background(), []string{"-batch", "0"}, strings.NewReader("")); err == nil {
+		t.Error("Esperava erro para -batch 0")
+	}
+	if err := runLocalCommand(context.Background(), []string{"/nao/existe.jsonl"}, nil); err == nil {
+		t.Error("Esperava erro para arquivo inexistente")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+
+	"finorbit/platform/logging"
+)
+
+// =========================================================
+// 📝 Logs estruturados
+// =========================================================
+
+// useLogBuffer direciona os logs JSON para um buffer durante o teste.
+func useLogBuffer(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(logging.New(&buf, level))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if raw == "" {
+			continue
+		}
+		var line map[string]any
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("Linha de log não é JSON: %q", raw)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestLogging_TransacaoSalvaComCampos(t *testing.T) {
+	buf := useLogBuffer(t, slog.LevelInfo)
+
+	resetDBSingleton()
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar mock: %v", err)
+	}
+	defer dbMock.Close()
+	expectLedgerPosting(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "10", "-10")
+
+	if err := processRecord(context.Background(), dbMock, events.SQSMessage{Body: rawEvent}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	var saved map[string]any
+	for _, line := range logLines(t, buf) {
+		if line["msg"] == "Transação salva" {
+			saved = line
+		}
+	}
+	if saved == nil {
+		t.Fatalf("Linha de sucesso ausente: %s", buf.String())
+	}
+	if saved[logKeyEventID] != "3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11" || saved[logKeyType] != "deposit" ||
+		saved[logKeyAmount] != "10" || saved[logKeyUserID] != "6f1c****3a4b" {
+		t.Errorf("Campos da transação incorretos: %v", saved)
+	}
+	if _, ok := saved[logKeyLatency]; !ok {
+		t.Errorf("Esperava latency_ms: %v", saved)
+	}
+}
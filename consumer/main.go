@@ -3,176 +3,510 @@ package main
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/XSAM/otelsql"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	_ "github.com/lib/pq"
-	"github.com/shopspring/decimal"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	txevents "finorbit/events"
+	"finorbit/platform/alerts"
+	"finorbit/platform/dbcreds"
+	"finorbit/platform/emf"
+	"finorbit/platform/logging"
+	"finorbit/platform/tracing"
 )
 
 // =========================================================
 // 💡 Estrutura de uma transação
 // =========================================================
-type Transaction struct {
-	UserID    string          `json:"user_id"`
-	Amount    decimal.Decimal `json:"amount"`
-	Type      string          `json:"type"`
-	Timestamp string          `json:"timestamp"`
-}
+// Transaction é o evento definido no contrato compartilhado (finorbit/events);
+// o alias mantém o nome usado no domínio do consumer.
+type Transaction = txevents.TransactionEvent
 
 // =========================================================
-// 🔒 Singleton da conexão com o banco
+// 🔒 Conexão com o banco, compartilhada pelo container
 // =========================================================
+// A conexão é aberta na primeira invocação que precisar dela. Se a
+// abertura falhar, db continua nil e a próxima invocação tenta de novo —
+// um banco fora do ar não condena o container.
 var (
 	db   *sql.DB
-	once sync.Once
+	dbMu sync.Mutex
+
+	// connectDB abre a conexão e aplica as migrações; os testes trocam
+	// por uma versão que simula falhas.
+	connectDB = initDB
+
+	// dbCredentials vem de DB_CREDENTIALS_SOURCE (ver finorbit/platform/dbcreds).
+	dbCredentials dbcreds.Provider = dbcreds.Env{}
+)
+
+// errDBDisabled indica GO_ENV=test: não há banco e não adianta insistir.
+var errDBDisabled = errors.New("conexão com o banco desativada em GO_ENV=test")
+
+// =========================================================
+// 🔁 Tentativas de conexão
+// =========================================================
+const (
+	dbConnectAttempts = 5
+	dbConnectMaxDelay = 5 * time.Second
+	// dbDeadlineMargin é o tempo reservado para devolver o lote em
+	// BatchItemFailures antes de a invocação estourar o prazo.
+	dbDeadlineMargin = time.Second
+	// dbColdStartTimeout limita a conexão antecipada na fase de init da Lambda.
+	dbColdStartTimeout = 5 * time.Second
 )
 
+// dbConnectBaseDelay é a espera antes da 2ª tentativa; dobra a cada nova falha.
+var dbConnectBaseDelay = 200 * time.Millisecond
+
+func dbConnectBackoff(attempt int) time.Duration {
+	delay := dbConnectBaseDelay << (attempt - 1)
+	if delay > dbConnectMaxDelay || delay <= 0 {
+		return dbConnectMaxDelay
+	}
+	return delay
+}
+
+// =========================================================
+// 🔌 Abre e valida a conexão com o Postgres
+// =========================================================
+func openDB(ctx context.Context) (*sql.DB, error) {
+	// DB_SSLMODE permite desligar o TLS num Postgres local (padrão: require)
+	sslMode := os.Getenv("DB_SSLMODE")
+	if sslMode == "" {
+		sslMode = "require"
+	}
+
+	// Cada conexão nova do pool busca a credencial atual, e cada comando
+	// SQL vira um span filho do processamento da mensagem
+	conn := otelsql.OpenDB(dbcreds.NewConnector(dbCredentials, sslMode),
+		otelsql.WithTracerProvider(tracerProvider),
+		otelsql.WithAttributes(attribute.String("db.system.name", "postgresql")),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
+
+	// Testa a conexão
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("falha ao conectar ao banco: %w", err)
+	}
+
+	return conn, nil
+}
+
+// initDB conecta e deixa o schema em dia; uma conexão que não passou
+// pelas migrações é descartada.
+func initDB(ctx context.Context) (*sql.DB, error) {
+	if os.Getenv("GO_ENV") == "test" {
+		return nil, errDBDisabled
+	}
+
+	conn, err := openDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := runMigrations(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("erro ao aplicar migrações: %w", err)
+	}
+	return conn, nil
+}
+
 // =========================================================
-// 🔧 Inicialização segura — executa 1x por container Lambda
+// 🔧 Inicialização com retentativas — reaproveitada pelo container
 // =========================================================
-func getDB() *sql.DB {
+// getDB devolve a conexão do container, abrindo-a se preciso. As
+// tentativas respeitam o prazo de ctx (o da invocação), deixando
+// dbDeadlineMargin para o handler devolver o lote.
+func getDB(ctx context.Context) (*sql.DB, error) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
 	if db != nil {
-		return db
+		return db, nil
 	}
-	
 
-	once.Do(func() {
-		if os.Getenv("GO_ENV") == "test" {
-			log.Println("🧪 Ambiente de teste detectado — conexão RDS ignorada.")
-			return
-		}
+	conn, err := connectWithRetry(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		connStr := fmt.Sprintf(
-			"host=%s user=%s password=%s dbname=%s sslmode=require",
-			os.Getenv("DB_HOST"),
-			os.Getenv("DB_USER"),
-			os.Getenv("DB_PASS"),
-			os.Getenv("DB_NAME"),
-		)
+	slog.InfoContext(ctx, "Conexão com RDS estabelecida")
+	db = conn
+	return db, nil
+}
 
-		var err error
-		db, err = sql.Open("postgres", connStr)
-		if err != nil {
-			log.Fatalf("❌ Erro ao inicializar conexão: %v", err)
+func connectWithRetry(ctx context.Context) (*sql.DB, error) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		var conn *sql.DB
+		conn, err = connectDB(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		if errors.Is(err, errDBDisabled) {
+			return nil, err
+		}
+		if attempt == dbConnectAttempts {
+			return nil, fmt.Errorf("banco indisponível após %d tentativas: %w", attempt, err)
 		}
 
-		// Testa a conexão
-		if err := db.Ping(); err != nil {
-			log.Fatalf("❌ Falha ao conectar ao banco: %v", err)
+		delay := dbConnectBackoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline)-delay < dbDeadlineMargin {
+			return nil, fmt.Errorf("banco indisponível após %d tentativas, sem prazo para outra: %w", attempt, err)
 		}
+		slog.WarnContext(ctx, "Falha ao conectar ao banco, nova tentativa",
+			"attempt", attempt, "retry_in_ms", delay.Milliseconds(), logKeyError, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("banco indisponível após %d tentativas: %w", attempt, errors.Join(err, ctx.Err()))
+		case <-time.After(delay):
+		}
+	}
+}
 
-		log.Println("✅ Conexão com RDS estabelecida com sucesso.")
-		ensureTableExists()
-	})
+// =========================================================
+// ⚠️ Classificação de falhas — temporárias x permanentes
+// =========================================================
+type failureKind int
+
+const (
+	// failureRetryable indica erro transitório (ex.: banco indisponível);
+	// a mensagem volta para a fila e será reprocessada.
+	failureRetryable failureKind = iota
+	// failurePermanent indica mensagem venenosa, que nunca será processada
+	// com sucesso; após esgotar as tentativas ela segue para a DLQ.
+	failurePermanent
+)
 
-	return db
+type processingError struct {
+	kind failureKind
+	err  error
+	// fromDB marca falhas de infraestrutura do banco; só elas contam para
+	// o alerta db_failures.
+	fromDB bool
+}
+
+func (e *processingError) Error() string { return e.err.Error() }
+func (e *processingError) Unwrap() error { return e.err }
+
+func retryable(err error) error {
+	return &processingError{kind: failureRetryable, err: err}
+}
+
+// dbRetryable é a falha temporária vinda do banco (conexão, comando SQL).
+func dbRetryable(err error) error {
+	return &processingError{kind: failureRetryable, err: err, fromDB: true}
+}
+
+func permanent(err error) error {
+	return &processingError{kind: failurePermanent, err: err}
+}
+
+func isPermanent(err error) bool {
+	var pe *processingError
+	return errors.As(err, &pe) && pe.kind == failurePermanent
+}
+
+func isDBFailure(err error) bool {
+	var pe *processingError
+	return errors.As(err, &pe) && pe.kind == failureRetryable && pe.fromDB
+}
+
+// classifyDBError separa erros do Postgres causados pelo conteúdo da
+// mensagem (dados inválidos, violação de constraint) dos erros de
+// infraestrutura, que merecem nova tentativa.
+func classifyDBError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "22", "23": // data_exception, integrity_constraint_violation
+			return permanent(err)
+		}
+	}
+	return dbRetryable(err)
 }
 
 // =========================================================
-// 🏗️ Garante que a tabela exista antes de inserir
+// 📨 Processamento de um único registro SQS
 // =========================================================
-func ensureTableExists() {
-	d := db
-	if d == nil {
-		return
+// processRecord identifica o formato do corpo, propaga o correlation_id e
+// registra o desfecho; o handler só decide o que volta em BatchItemFailures.
+func processRecord(ctx context.Context, d *sql.DB, record events.SQSMessage) error {
+	start := time.Now()
+	msg, err := parseRecord(record)
+
+	ctx, span := startRecordSpan(ctx, record, msg.Attributes)
+	defer span.End()
+
+	if err != nil {
+		err = permanent(err)
+	} else {
+		ctx = logging.WithCorrelationID(ctx, correlationIDFromAttributes(msg.Attributes))
+		span.SetAttributes(attribute.String(correlationAttribute, logging.CorrelationIDFromContext(ctx)))
+		err = processMessage(ctx, d, record, msg)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	switch {
+	case isPermanent(err):
+		metrics.Record(metricMessagesDropped, 1, emf.UnitCount, nil)
+		slog.ErrorContext(ctx, "Mensagem venenosa",
+			"message_id", record.MessageId, logKeyError, err, logKeyLatency, time.Since(start).Milliseconds())
+		alertPoison(ctx, record, err)
+	case err != nil:
+		metrics.Record(metricMessagesRetried, 1, emf.UnitCount, nil)
+		slog.WarnContext(ctx, "Falha temporária, mensagem será reprocessada",
+			"message_id", record.MessageId, logKeyError, err, logKeyLatency, time.Since(start).Milliseconds())
+		// Outras falhas temporárias (ex.: certificado SNS) não são do banco
+		if isDBFailure(err) {
+			recordDBFailure(ctx, err)
+		}
+	default:
+		dbFailures.Reset()
 	}
+	return err
+}
 
-	checkQuery := `
-	SELECT EXISTS (
-		SELECT FROM information_schema.tables
-		WHERE table_schema = 'public' AND table_name = 'transactions'
-	);
-	`
+// processMessage verifica, decodifica e grava uma mensagem já identificada;
+// ctx carrega o correlation_id lido dos atributos.
+func processMessage(ctx context.Context, d *sql.DB, record events.SQSMessage, msg inboundMessage) error {
+	start := time.Now()
 
-	var exists bool
-	if err := d.QueryRow(checkQuery).Scan(&exists); err != nil {
-		log.Fatalf("❌ Erro ao verificar existência da tabela: %v", err)
+	// Rejeita envelopes forjados antes de qualquer outro processamento.
+	// Só o envelope SNS é assinado: com a verificação ligada, corpos raw
+	// não podem ser autenticados e são recusados.
+	if signatureVerifier != nil {
+		if msg.Format != bodySNS {
+			return permanent(fmt.Errorf("%w: mensagem %s sem envelope SNS", errSignatureInvalid, msg.Format))
+		}
+		if err := signatureVerifier.Verify(ctx, []byte(record.Body)); err != nil {
+			if errors.As(err, new(*processingError)) {
+				return err
+			}
+			return permanent(err)
+		}
 	}
 
-	if exists {
-		log.Println("📦 Tabela 'transactions' já existe — sem alterações.")
-		return
+	tx, err := decodeTransaction(msg.Payload)
+	if err != nil {
+		err = permanent(fmt.Errorf("transação inválida: %w", err))
+		// Evento identificável mas fora do contrato: a consulta de status responde "failed"
+		if tx.EventID != "" && d != nil {
+			recordFailure(ctx, d, tx.EventID, err)
+		}
+		return err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("finorbit.event_id", tx.EventID),
+		attribute.String("finorbit.transaction.type", tx.Type),
+	)
+	logger := slog.Default().With(transactionLogAttrs(tx)...)
+
+	// O atributo `type` decide a fila de destino; se divergir do corpo,
+	// a mensagem foi montada à mão ou adulterada
+	if attrType, ok := msg.Attributes["type"]; ok && attrType != tx.Type {
+		return permanent(fmt.Errorf("atributo type %q difere do evento (%q)", attrType, tx.Type))
+	}
+
+	err = persistTransaction(ctx, d, tx)
+	if errors.Is(err, errDuplicateEvent) {
+		// Redelivery do SQS ou retentativa do cliente: o evento já foi gravado
+		metrics.Record(metricDuplicatesIgnored, 1, emf.UnitCount, nil)
+		logger.InfoContext(ctx, "Transação duplicada ignorada")
+		return nil
+	}
+	if errors.Is(err, errInsufficientFunds) {
+		metrics.Record(metricTransactionsDenied, 1, emf.UnitCount, emf.TypeDims(tx.Type))
+		logger.InfoContext(ctx, "Saque rejeitado por saldo insuficiente")
+		alertRejectedWithdrawal(ctx, tx)
+		return nil
+	}
+	if err != nil {
+		if isPermanent(err) && tx.EventID != "" {
+			recordFailure(ctx, d, tx.EventID, err)
+		}
+		return err
 	}
 
-	createQuery := `
-	CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+	recordPersisted(tx, time.Now())
+	logger.InfoContext(ctx, "Transação salva", logKeyLatency, time.Since(start).Milliseconds())
+	return nil
+}
 
-	CREATE TABLE public.transactions (
-		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-		user_id UUID NOT NULL,
-		amount NUMERIC(12,2) NOT NULL,
-		type VARCHAR(50) NOT NULL,
-		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	`
+// recordPersisted soma a transação ao volume e ao valor do seu tipo e mede
+// a latência ponta a ponta, do Timestamp gerado no producer até a gravação.
+func recordPersisted(tx Transaction, now time.Time) {
+	dims := emf.TypeDims(tx.Type)
+	metrics.Record(metricMessagesPersisted, 1, emf.UnitCount, dims)
+	metrics.Record(metricTransactionAmount, tx.Amount.InexactFloat64(), emf.UnitNone, dims)
 
-	if _, err := d.Exec(createQuery); err != nil {
-		log.Fatalf("❌ Erro ao criar tabela 'transactions': %v", err)
+	// Eventos legados podem vir sem Timestamp
+	if created, err := time.Parse(time.RFC3339, tx.Timestamp); err == nil {
+		metrics.Record(metricEndToEndLatency, float64(now.Sub(created).Milliseconds()), emf.UnitMilliseconds, dims)
 	}
+}
 
-	log.Println("✅ Tabela 'transactions' criada com sucesso!")
+// transactionLogAttrs são os campos da transação repetidos nas linhas de log.
+func transactionLogAttrs(tx Transaction) []any {
+	return []any{
+		logKeyEventID, tx.EventID,
+		logKeyUserID, tx.UserID,
+		logKeyType, tx.Type,
+		logKeyAmount, tx.Amount.String(),
+	}
 }
 
 // =========================================================
 // 📬 Função Lambda — processa mensagens SQS (via SNS)
 // =========================================================
-func handler(ctx context.Context, sqsEvent events.SQSEvent) error {
-	log.Println("🚀 Iniciando processamento de mensagens...")
+// Retorna apenas os registros que falharam em BatchItemFailures, para que
+// o SQS apague somente o que foi efetivamente persistido.
+func handler(ctx context.Context, sqsEvent events.SQSEvent) (events.SQSEventResponse, error) {
+	defer tracing.Flush(ctx, tracerProvider)
+	defer flushMetrics(ctx)
+	defer flushAlerts(ctx)
+	ctx, span := tracer().Start(ctx, "transactions process batch",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.Int("messaging.batch.message_count", len(sqsEvent.Records))),
+	)
+	defer span.End()
 
-	d := getDB()
-	if d == nil {
-		log.Println("⚠️ Banco não inicializado — abortando execução.")
-		return nil
-	}
+	slog.InfoContext(ctx, "Iniciando processamento do lote", "records", len(sqsEvent.Records))
 
-	for _, record := range sqsEvent.Records {
-		// As mensagens vêm do SNS → SQS
-		var snsEnvelope events.SNSEntity
-		if err := json.Unmarshal([]byte(record.Body), &snsEnvelope); err != nil {
-			log.Printf("⚠️ Erro ao decodificar envelope SNS: %v", err)
-			continue
-		}
+	var resp events.SQSEventResponse
 
-		var tx Transaction
-		if err := json.Unmarshal([]byte(snsEnvelope.Message), &tx); err != nil {
-			log.Printf("⚠️ Erro ao decodificar transação: %v", err)
-			continue
+	d, err := getDB(ctx)
+	if err != nil {
+		// O lote volta inteiro para a fila; o processo segue vivo e a
+		// próxima invocação tenta conectar de novo
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		slog.ErrorContext(ctx, "Banco indisponível, lote devolvido para nova tentativa", logKeyError, err)
+		recordDBFailure(ctx, err)
+		for _, record := range sqsEvent.Records {
+			resp.BatchItemFailures = append(resp.BatchItemFailures,
+				events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
 		}
+		return resp, nil
+	}
 
-		_, err := d.Exec(
-			`INSERT INTO transactions (user_id, amount, type, timestamp)
-			 VALUES ($1, $2, $3, $4)`,
-			tx.UserID, tx.Amount.String(), tx.Type, tx.Timestamp,
-		)
-		if err != nil {
-			log.Printf("❌ Erro ao salvar transação no banco: %v", err)
+	for _, record := range sqsEvent.Records {
+		if err := processRecord(ctx, d, record); err == nil {
 			continue
 		}
-
-		log.Printf("✅ Transação salva com sucesso | user=%s | tipo=%s | valor=%s",
-			tx.UserID, tx.Type, tx.Amount.String())
+		resp.BatchItemFailures = append(resp.BatchItemFailures,
+			events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
 	}
 
-	return nil
+	return resp, nil
 }
 
 // =========================================================
 // 🚀 Ponto de entrada da Lambda
 // =========================================================
 func main() {
+	if err := logging.Setup(); err != nil {
+		logging.Fatal("Erro ao configurar logs", err)
+	}
+	if err := setupTracing(context.Background()); err != nil {
+		logging.Fatal("Erro ao configurar tracing", err)
+	}
+	defer tracing.Shutdown(context.Background(), tracerProvider)
+
+	// Carrega configuração AWS (Secrets Manager e alertas)
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		logging.Fatal("Erro ao carregar configuração AWS", err)
+	}
+
+	dbCredentials, err = dbcreds.NewProvider(cfg)
+	if err != nil {
+		logging.Fatal("Erro ao configurar credenciais do banco", err)
+	}
+
+	// Execução fora da Lambda: `bootstrap migrate up|down|status`
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		conn, err := openDB(context.Background())
+		if err != nil {
+			logging.Fatal("Erro ao conectar ao banco", err)
+		}
+		defer conn.Close()
+
+		if err := runMigrateCommand(context.Background(), conn, os.Args[2:]); err != nil {
+			logging.Fatal("Erro no comando migrate", err)
+		}
+		return
+	}
+
+	// Execução local: `bootstrap run [arquivo.jsonl|-]` alimenta o handler
+	// a partir de um arquivo ou do stdin, sem SQS
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		conn, err := openDB(ctx)
+		if err != nil {
+			logging.Fatal("Erro ao conectar ao banco", err)
+		}
+		defer conn.Close()
+
+		if err := runMigrations(ctx, conn); err != nil {
+			logging.Fatal("Erro ao aplicar migrações", err)
+		}
+		db = conn
+
+		if err := runLocalCommand(ctx, os.Args[2:], os.Stdin); err != nil {
+			logging.Fatal("Erro na execução local", err)
+		}
+		return
+	}
+
 	if os.Getenv("GO_ENV") == "test" {
-		log.Println("🧪 Modo de teste — Lambda não será iniciado.")
+		slog.Info("Modo de teste, Lambda não será iniciado")
 		return
 	}
 
-	// Garante que a conexão seja inicializada no primeiro cold start
-	getDB()
+	m, err := emf.New(metricsService)
+	if err != nil {
+		logging.Fatal("Erro ao configurar métricas", err)
+	}
+	metrics = m
+
+	alerter = alerts.New(cfg, metricsService)
+	if largeWithdrawalThreshold, err = largeWithdrawalThresholdFromEnv(); err != nil {
+		logging.Fatal("Erro ao configurar alertas", err)
+	}
+
+	signatureVerifier = signatureVerifierFromEnv()
+	if signatureVerifier != nil {
+		slog.Info("Verificação de assinatura SNS habilitada")
+	}
+
+	// Adianta a conexão no cold start; se falhar, o handler tenta de novo
+	ctx, cancel := context.WithTimeout(context.Background(), dbColdStartTimeout)
+	if _, err := getDB(ctx); err != nil {
+		slog.Warn("Banco indisponível no cold start, nova tentativa na primeira invocação", logKeyError, err)
+	}
+	cancel()
 
 	lambda.Start(handler)
 }
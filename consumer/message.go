@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// =========================================================
+// 📦 Formato do corpo da mensagem SQS
+// =========================================================
+//
+// O corpo pode chegar de três formas:
+//   - envelope SNS (assinatura padrão SNS → SQS);
+//   - JSON do evento puro (raw message delivery ou envio direto à fila);
+//   - CloudEvent estruturado (idem, com o producer em EVENT_FORMAT=cloudevents).
+//
+// Em todos os casos o payload segue para decodeTransaction, e os atributos
+// SNS são preservados — do envelope ou, na entrega raw, dos atributos SQS.
+
+const (
+	bodySNS         = "sns"
+	bodyRaw         = "raw"
+	bodyCloudEvents = "cloudevents"
+)
+
+var errUnknownBody = errors.New("formato de mensagem desconhecido")
+
+type inboundMessage struct {
+	Format     string
+	Payload    []byte
+	Attributes map[string]string
+}
+
+type snsAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+// parseRecord identifica o formato pelo conteúdo, já que o SQS não informa
+// se a assinatura usa raw message delivery.
+func parseRecord(record events.SQSMessage) (inboundMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(record.Body), &fields); err != nil {
+		return inboundMessage{}, fmt.Errorf("%w: corpo não é um objeto JSON", errUnknownBody)
+	}
+
+	switch {
+	case fields["Message"] != nil:
+		var envelope struct {
+			Message           string                  `json:"Message"`
+			MessageAttributes map[string]snsAttribute `json:"MessageAttributes"`
+		}
+		if err := json.Unmarshal([]byte(record.Body), &envelope); err != nil {
+			return inboundMessage{}, fmt.Errorf("envelope SNS inválido: %w", err)
+		}
+
+		attributes := make(map[string]string, len(envelope.MessageAttributes))
+		for name, attr := range envelope.MessageAttributes {
+			attributes[name] = attr.Value
+		}
+		return inboundMessage{Format: bodySNS, Payload: []byte(envelope.Message), Attributes: attributes}, nil
+
+	case fields["specversion"] != nil:
+		return inboundMessage{Format: bodyCloudEvents, Payload: []byte(record.Body), Attributes: sqsAttributes(record)}, nil
+
+	case fields["event_id"] != nil || fields["user_id"] != nil || fields["schema_version"] != nil:
+		return inboundMessage{Format: bodyRaw, Payload: []byte(record.Body), Attributes: sqsAttributes(record)}, nil
+
+	default:
+		return inboundMessage{}, errUnknownBody
+	}
+}
+
+// sqsAttributes lê os atributos que o SNS repassa como atributos SQS na
+// entrega raw.
+func sqsAttributes(record events.SQSMessage) map[string]string {
+	attributes := make(map[string]string, len(record.MessageAttributes))
+	for name, attr := range record.MessageAttributes {
+		if attr.StringValue != nil {
+			attributes[name] = *attr.StringValue
+		}
+	}
+	return attributes
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// =========================================================
+// 📦 Formato do corpo
+// =========================================================
+const rawEvent = `{"schema_version":1,"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"10.00","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`
+
+func stringAttribute(value string) events.SQSMessageAttribute {
+	return events.SQSMessageAttribute{DataType: "String", StringValue: &value}
+}
+
+func TestParseRecord_EnvelopeSNS(t *testing.T) {
+	body, _ := snsEnvelope(rawEvent, map[string]string{"type": "deposit"})
+
+	msg, err := parseRecord(events.SQSMessage{Body: body})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if msg.Format != bodySNS || string(msg.Payload) != rawEvent {
+		t.Errorf("Envelope SNS identificado incorretamente: %+v", msg)
+	}
+	if msg.Attributes["type"] != "deposit" {
+		t.Errorf("Atributos do envelope perdidos: %v", msg.Attributes)
+	}
+}
+
+func TestParseRecord_EntregaRawPreservaAtributosSQS(t *testing.T) {
+	record := events.SQSMessage{
+		Body:              rawEvent,
+		MessageAttributes: map[string]events.SQSMessageAttribute{"type": stringAttribute("deposit")},
+	}
+
+	msg, err := parseRecord(record)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if msg.Format != bodyRaw || string(msg.Payload) != rawEvent {
+		t.Errorf("Corpo raw identificado incorretamente: %+v", msg)
+	}
+	if msg.Attributes["type"] != "deposit" {
+		t.Errorf("Atributos SQS perdidos: %v", msg.Attributes)
+	}
+}
+
+func TestParseRecord_CloudEventRaw(t *testing.T) {
+	ce := `{"specversion":"1.0","id":"e-1","source":"urn:teste","type":"finorbit.transaction.deposit","data":{}}`
+
+	msg, err := parseRecord(events.SQSMessage{Body: ce})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if msg.Format != bodyCloudEvents {
+		t.Errorf("Esperava formato %q, obteve %q", bodyCloudEvents, msg.Format)
+	}
+}
+
+func TestParseRecord_FormatoDesconhecido(t *testing.T) {
+	for _, body := range []string{"mensagem inválida", `{"Data":"valor"}`, `[1,2]`} {
+		if _, err := parseRecord(events.SQSMessage{Body: body}); !errors.Is(err, errUnknownBody) {
+			t.Errorf("%s: esperava errUnknownBody, obteve %v", body, err)
+		}
+	}
+}
+
+func TestProcessRecord_EntregaRawGravaTransacao(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar mock: %v", err)
+	}
+	defer dbMock.Close()
+
+	expectLedgerPosting(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "10", "-10")
+
+	if err := processRecord(context.Background(), dbMock, events.SQSMessage{Body: rawEvent}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestProcessRecord_AtributoTypeDivergenteEhPermanente(t *testing.T) {
+	record := events.SQSMessage{
+		Body:              rawEvent,
+		MessageAttributes: map[string]events.SQSMessageAttribute{"type": stringAttribute("withdraw")},
+	}
+
+	if err := processRecord(context.Background(), nil, record); !isPermanent(err) {
+		t.Errorf("Esperava falha permanente, obteve %v", err)
+	}
+}
+
+func TestProcessRecord_RawRecusadoComVerificacaoLigada(t *testing.T) {
+	signatureVerifier = newSNSSignatureVerifier(&fakeCertificateFetcher{}, nil)
+	t.Cleanup(func() { signatureVerifier = nil })
+
+	err := processRecord(context.Background(), nil, events.SQSMessage{Body: rawEvent})
+	if !isPermanent(err) || !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("Esperava falha permanente de assinatura, obteve %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"finorbit/platform/emf"
+)
+
+// =========================================================
+// 📈 Métricas de negócio (CloudWatch EMF)
+// =========================================================
+//
+// Writer EMF, backends (METRICS_BACKEND/METRICS_NAMESPACE) e o registro em
+// memória dos testes ficam em finorbit/platform/emf.
+const metricsService = "consumer"
+
+// Nomes das métricas do consumer.
+const (
+	metricMessagesPersisted  = "MessagesPersisted"
+	metricMessagesDropped    = "MessagesDropped"
+	metricMessagesRetried    = "MessagesRetried"
+	metricDuplicatesIgnored  = "DuplicatesIgnored"
+	metricTransactionsDenied = "TransactionsRejected"
+	metricTransactionAmount  = "TransactionAmount"
+	metricEndToEndLatency    = "EndToEndLatency"
+)
+
+var metrics emf.Metrics = emf.Noop{}
+
+// flushMetrics publica as métricas da invocação; uma falha aqui não deve
+// devolver mensagens já gravadas para a fila.
+func flushMetrics(ctx context.Context) {
+	if err := metrics.Flush(ctx); err != nil {
+		slog.WarnContext(ctx, "Erro ao publicar métricas", logKeyError, err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/shopspring/decimal"
+
+	"finorbit/platform/emf"
+)
+
+// =========================================================
+// 📈 Métricas
+// =========================================================
+
+// useMemoryMetrics registra as métricas em memória durante o teste.
+func useMemoryMetrics(t *testing.T) *emf.Memory {
+	t.Helper()
+	m := emf.NewMemory()
+	metrics = m
+	t.Cleanup(func() { metrics = emf.Noop{} })
+	return m
+}
+
+func TestRecordPersisted_LatenciaPontaAPonta(t *testing.T) {
+	m := useMemoryMetrics(t)
+	tx := Transaction{Type: "deposit", Amount: decimal.RequireFromString("12.5"), Timestamp: "2025-11-07T00:00:00Z"}
+
+	recordPersisted(tx, time.Date(2025, 11, 7, 0, 0, 2, 0, time.UTC))
+
+	if m.Sum(metricEndToEndLatency) != 2000 {
+		t.Errorf("Esperava latência de 2000ms, obteve %v", m.Sum(metricEndToEndLatency))
+	}
+	if m.Sum(metricMessagesPersisted) != 1 || m.Sum(metricTransactionAmount) != 12.5 {
+		t.Errorf("Volume/valor incorretos: %+v", m.Records())
+	}
+
+	// Sem Timestamp (evento legado) não há latência a medir
+	recordPersisted(Transaction{Type: "deposit"}, time.Now())
+	if got := len(m.Records()); got != 5 {
+		t.Errorf("Esperava 5 registros, obteve %d", got)
+	}
+}
+
+func TestMetrics_PersistidaEDescartada(t *testing.T) {
+	m := useMemoryMetrics(t)
+
+	resetDBSingleton()
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar mock: %v", err)
+	}
+	defer dbMock.Close()
+	db = dbMock
+	expectLedgerPosting(mock, "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b", "10", "-10")
+
+	handler(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "ok", Body: rawEvent},
+		{MessageId: "veneno", Body: "mensagem inválida"},
+	}})
+
+	if m.Sum(metricMessagesPersisted) != 1 || m.Sum(metricMessagesDropped) != 1 {
+		t.Errorf("Esperava 1 persistida e 1 descartada, obteve %+v", m.Records())
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// =========================================================
+// 🗂️ Migrações versionadas — embutidas no binário
+// =========================================================
+//
+// Cada versão tem um par de arquivos em migrations/:
+//
+//	0001_create_transactions.up.sql
+//	0001_create_transactions.down.sql
+//
+// As versões aplicadas ficam registradas em schema_migrations.
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID identifica o advisory lock do Postgres que serializa
+// cold starts concorrentes (consumer_deposit e consumer_withdraw).
+const migrationLockID int64 = 4_617_239_018
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// loadMigrations lê os arquivos *.up.sql / *.down.sql e devolve as
+// migrações ordenadas por versão.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	paths, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, p := range paths {
+		file := path.Base(p)
+
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migração %s sem sufixo .up.sql/.down.sql", file)
+		}
+
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migração %s fora do padrão <versão>_<nome>", file)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("versão inválida na migração %s", file)
+		}
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+
+		m, found := byVersion[version]
+		if !found {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		} else if m.name != name {
+			return nil, fmt.Errorf("versão %d duplicada (%s e %s)", version, m.name, name)
+		}
+
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migração %04d_%s sem arquivo .up.sql", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
+
+// =========================================================
+// 🔐 Execução protegida por advisory lock
+// =========================================================
+// withMigrationLock mantém uma conexão dedicada durante toda a migração,
+// já que advisory locks de sessão pertencem à conexão que os adquiriu.
+func withMigrationLock(ctx context.Context, d *sql.DB, fn func(conn *sql.Conn) error) error {
+	conn, err := d.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("erro ao obter conexão para migração: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("erro ao adquirir lock de migração: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			slog.Warn("Erro ao liberar lock de migração", logKeyError, err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS public.schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+	`); err != nil {
+		return fmt.Errorf("erro ao criar schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// queryer cobre *sql.DB e *sql.Conn: o status lê sem conexão dedicada.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func appliedVersions(ctx context.Context, conn queryer) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM public.schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// applyMigration executa o SQL e atualiza schema_migrations na mesma
+// transação, para que uma falha nunca deixe a versão meio aplicada.
+func applyMigration(ctx context.Context, conn *sql.Conn, script, bookkeeping string, version int, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, append([]any{version}, args...)...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// =========================================================
+// ⬆️⬇️ Up / Down / Status
+// =========================================================
+func migrateUp(ctx context.Context, d *sql.DB, migrations []migration) error {
+	return withMigrationLock(ctx, d, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if applied[m.version] {
+				continue
+			}
+			err := applyMigration(ctx, conn, m.up,
+				`INSERT INTO public.schema_migrations (version, name) VALUES ($1, $2)`,
+				m.version, m.name)
+			if err != nil {
+				return fmt.Errorf("erro ao aplicar migração %04d_%s: %w", m.version, m.name, err)
+			}
+			slog.InfoContext(ctx, "Migração aplicada", "version", m.version, "name", m.name)
+		}
+		return nil
+	})
+}
+
+// migrateDown reverte as últimas `steps` migrações aplicadas.
+func migrateDown(ctx context.Context, d *sql.DB, migrations []migration, steps int) error {
+	return withMigrationLock(ctx, d, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if !applied[m.version] {
+				continue
+			}
+			if m.down == "" {
+				return fmt.Errorf("migração %04d_%s não possui .down.sql", m.version, m.name)
+			}
+			err := applyMigration(ctx, conn, m.down,
+				`DELETE FROM public.schema_migrations WHERE version = $1`,
+				m.version)
+			if err != nil {
+				return fmt.Errorf("erro ao reverter migração %04d_%s: %w", m.version, m.name, err)
+			}
+			slog.InfoContext(ctx, "Migração revertida", "version", m.version, "name", m.name)
+			steps--
+		}
+		return nil
+	})
+}
+
+// migrationStatus só lê: não cria schema_migrations nem espera o advisory
+// lock, para não ficar preso atrás de uma migração em andamento.
+func migrationStatus(ctx context.Context, d *sql.DB, migrations []migration) ([]string, error) {
+	var exists bool
+	if err := d.QueryRowContext(ctx, `SELECT to_regclass('public.schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("erro ao consultar schema_migrations: %w", err)
+	}
+
+	applied := map[int]bool{}
+	var lines []string
+	if exists {
+		var err error
+		if applied, err = appliedVersions(ctx, d); err != nil {
+			return nil, err
+		}
+	} else {
+		lines = append(lines, "nenhuma migração aplicada")
+	}
+
+	for _, m := range migrations {
+		state := "pendente"
+		if applied[m.version] {
+			state = "aplicada"
+		}
+		lines = append(lines, fmt.Sprintf("%04d_%s\t%s", m.version, m.name, state))
+	}
+	return lines, nil
+}
+
+// =========================================================
+// 🏗️ Migração automática no cold start
+// =========================================================
+func runMigrations(ctx context.Context, d *sql.DB) error {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return fmt.Errorf("erro ao carregar migrações: %w", err)
+	}
+	return migrateUp(ctx, d, migrations)
+}
+
+// =========================================================
+// 🛠️ Comando de migração fora da Lambda
+// =========================================================
+//
+//	go run . migrate up
+//	go run . migrate down [passos]
+//	go run . migrate status
+func runMigrateCommand(ctx context.Context, d *sql.DB, args []string) error {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return fmt.Errorf("erro ao carregar migrações: %w", err)
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("uso: migrate up | down [passos] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		return migrateUp(ctx, d, migrations)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("número de passos inválido: %q", args[1])
+			}
+		}
+		return migrateDown(ctx, d, migrations, steps)
+	case "status":
+		lines, err := migrationStatus(ctx, d, migrations)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		return nil
+	default:
+		return fmt.Errorf("subcomando de migração desconhecido: %q", args[0])
+	}
+}
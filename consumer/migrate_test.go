@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+// =========================================================
+// 🗂️ Leitura dos arquivos de migração
+// =========================================================
+func TestLoadMigrations_Embutidas(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatalf("Erro ao carregar migrações embutidas: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Nenhuma migração embutida encontrada")
+	}
+
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("Esperava versão %d na posição %d, obteve %d", i+1, i, m.version)
+		}
+		if m.up == "" || m.down == "" {
+			t.Errorf("Migração %04d_%s sem up/down", m.version, m.name)
+		}
+	}
+}
+
+func TestLoadMigrations_Invalidas(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"sem direção":  {"migrations/0001_x.sql": {Data: []byte("SELECT 1")}},
+		"sem versão":   {"migrations/abc_x.up.sql": {Data: []byte("SELECT 1")}},
+		"sem up":       {"migrations/0001_x.down.sql": {Data: []byte("SELECT 1")}},
+		"nome diverge": {"migrations/0001_a.up.sql": {Data: []byte("SELECT 1")}, "migrations/0001_b.up.sql": {Data: []byte("SELECT 1")}},
+	}
+
+	for name, fsys := range cases {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Errorf("%s: esperava erro, obteve nil", name)
+		}
+	}
+}
+
+// =========================================================
+// ⬆️ Aplicação das migrações pendentes
+// =========================================================
+var testMigrations = []migration{
+	{version: 1, name: "create_transactions", up: "CREATE TABLE t1", down: "DROP TABLE t1"},
+	{version: 2, name: "add_event_id", up: "ALTER TABLE t2", down: "ALTER TABLE t2 DROP"},
+}
+
+func expectMigrationLock(mock sqlmock.Sqlmock, applied ...int64) {
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS public.schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version"})
+	for _, v := range applied {
+		rows.AddRow(v)
+	}
+	mock.ExpectQuery(`SELECT version FROM public.schema_migrations`).WillReturnRows(rows)
+}
+
+func TestMigrateUp_AplicaSomentePendentes(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	expectMigrationLock(mock, 1)
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE t2`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO public.schema_migrations`).WithArgs(2, "add_event_id").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := migrateUp(context.Background(), dbMock, testMigrations); err != nil {
+		t.Fatalf("migrateUp retornou erro: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestMigrateUp_FalhaFazRollback(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	expectMigrationLock(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE t1`).WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := migrateUp(context.Background(), dbMock, testMigrations); err == nil {
+		t.Fatal("Esperava erro ao aplicar migração inválida")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestMigrateUp_LockIndisponivel(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnError(errors.New("connection refused"))
+
+	if err := migrateUp(context.Background(), dbMock, testMigrations); err == nil {
+		t.Fatal("Esperava erro quando o lock não pode ser adquirido")
+	}
+}
+
+// =========================================================
+// ⬇️ Reversão
+// =========================================================
+func TestMigrateDown_RevertUltima(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	expectMigrationLock(mock, 1, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE t2 DROP`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM public.schema_migrations`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := migrateDown(context.Background(), dbMock, testMigrations, 1); err != nil {
+		t.Fatalf("migrateDown retornou erro: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+// =========================================================
+// 🛠️ Comando migrate
+// =========================================================
+func TestRunMigrateCommand_ArgumentosInvalidos(t *testing.T) {
+	dbMock, _, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	for _, args := range [][]string{nil, {"sideways"}, {"down", "zero"}} {
+		if err := runMigrateCommand(context.Background(), dbMock, args); err == nil {
+			t.Errorf("Esperava erro para argumentos %v", args)
+		}
+	}
+}
+
+func TestRunMigrateCommand_Status(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	// Somente leitura: sem advisory lock e sem CREATE TABLE
+	mock.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT version FROM public.schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+
+	if err := runMigrateCommand(context.Background(), dbMock, []string{"status"}); err != nil {
+		t.Fatalf("status retornou erro: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestMigrationStatus_SemTabelaNenhumaAplicada(t *testing.T) {
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+
+	mock.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	lines, err := migrationStatus(context.Background(), dbMock, testMigrations)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	want := []string{"nenhuma migração aplicada", "0001_create_transactions\tpendente", "0002_add_event_id\tpendente"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Esperava %q, obteve %q", want, lines)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.transactions_event_id_key;
+
+ALTER TABLE public.transactions DROP COLUMN IF EXISTS event_id;
@@ -0,0 +1,3 @@
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS event_id UUID;
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_event_id_key ON public.transactions (event_id);
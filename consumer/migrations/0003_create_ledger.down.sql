@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS postings_balanced ON public.postings;
+DROP FUNCTION IF EXISTS public.check_journal_balanced();
+
+DROP TABLE IF EXISTS public.postings;
+DROP TABLE IF EXISTS public.journal_entries;
+DROP TABLE IF EXISTS public.accounts;
@@ -0,0 +1,73 @@
+-- Contas: o saldo é mantido junto ao lançamento, mas sempre pode ser
+-- recalculado como a soma das postings da conta.
+CREATE TABLE IF NOT EXISTS public.accounts (
+	id UUID PRIMARY KEY,
+	kind VARCHAR(20) NOT NULL DEFAULT 'user',
+	balance NUMERIC(14,2) NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Contrapartida de todo dinheiro que entra ou sai do sistema
+INSERT INTO public.accounts (id, kind)
+VALUES ('00000000-0000-0000-0000-000000000001', 'system')
+ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.journal_entries (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	transaction_id UUID NOT NULL UNIQUE REFERENCES public.transactions (id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS public.postings (
+	id BIGSERIAL PRIMARY KEY,
+	journal_entry_id UUID NOT NULL REFERENCES public.journal_entries (id),
+	account_id UUID NOT NULL REFERENCES public.accounts (id),
+	amount NUMERIC(14,2) NOT NULL CHECK (amount <> 0),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS postings_account_id_idx ON public.postings (account_id, id);
+CREATE INDEX IF NOT EXISTS postings_journal_entry_id_idx ON public.postings (journal_entry_id);
+
+-- Partidas dobradas: ao final da transação, as postings de cada
+-- lançamento precisam somar zero.
+CREATE OR REPLACE FUNCTION public.check_journal_balanced() RETURNS trigger AS $$
+BEGIN
+	IF (SELECT COALESCE(SUM(amount), 0) FROM public.postings WHERE journal_entry_id = NEW.journal_entry_id) <> 0 THEN
+		RAISE EXCEPTION 'lançamento % não soma zero', NEW.journal_entry_id USING ERRCODE = 'check_violation';
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS postings_balanced ON public.postings;
+CREATE CONSTRAINT TRIGGER postings_balanced
+	AFTER INSERT OR UPDATE ON public.postings
+	DEFERRABLE INITIALLY DEFERRED
+	FOR EACH ROW EXECUTE FUNCTION public.check_journal_balanced();
+
+-- Backfill: transações gravadas antes da existência do ledger
+INSERT INTO public.accounts (id)
+SELECT DISTINCT user_id FROM public.transactions
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO public.journal_entries (transaction_id)
+SELECT t.id FROM public.transactions t
+WHERE NOT EXISTS (SELECT 1 FROM public.journal_entries j WHERE j.transaction_id = t.id);
+
+INSERT INTO public.postings (journal_entry_id, account_id, amount)
+SELECT j.id, t.user_id, CASE WHEN t.type = 'withdraw' THEN -t.amount ELSE t.amount END
+FROM public.journal_entries j
+JOIN public.transactions t ON t.id = j.transaction_id
+WHERE NOT EXISTS (SELECT 1 FROM public.postings p WHERE p.journal_entry_id = j.id)
+UNION ALL
+SELECT j.id, '00000000-0000-0000-0000-000000000001', CASE WHEN t.type = 'withdraw' THEN t.amount ELSE -t.amount END
+FROM public.journal_entries j
+JOIN public.transactions t ON t.id = j.transaction_id
+WHERE NOT EXISTS (SELECT 1 FROM public.postings p WHERE p.journal_entry_id = j.id);
+
+UPDATE public.accounts a
+SET balance = s.total, updated_at = now()
+FROM (SELECT account_id, SUM(amount) AS total FROM public.postings GROUP BY account_id) s
+WHERE a.id = s.account_id;
@@ -0,0 +1,2 @@
+ALTER TABLE public.transactions DROP COLUMN IF EXISTS rejection_reason;
+ALTER TABLE public.transactions DROP COLUMN IF EXISTS status;
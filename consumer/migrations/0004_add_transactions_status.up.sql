@@ -0,0 +1,3 @@
+-- Resultado do processamento: persisted (lançada no ledger) ou rejected
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'persisted';
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
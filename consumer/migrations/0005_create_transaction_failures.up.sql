@@ -0,0 +1,9 @@
+-- Eventos descartados por falha permanente (mensagem venenosa), para que a
+-- consulta de status consiga responder "failed".
+CREATE TABLE IF NOT EXISTS public.transaction_failures (
+	event_id UUID PRIMARY KEY,
+	reason TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 1,
+	first_failed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_failed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
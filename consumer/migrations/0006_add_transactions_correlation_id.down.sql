@@ -0,0 +1 @@
+ALTER TABLE public.transactions DROP COLUMN IF EXISTS correlation_id;
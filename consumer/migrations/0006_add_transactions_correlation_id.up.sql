@@ -0,0 +1,2 @@
+-- Correlation ID da requisição que originou o evento (NULL em eventos antigos)
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS correlation_id TEXT;
@@ -0,0 +1,6 @@
+COMMENT ON COLUMN public.accounts.balance IS NULL;
+
+UPDATE public.accounts a
+SET balance = COALESCE((SELECT SUM(p.amount) FROM public.postings p WHERE p.account_id = a.id), 0),
+	updated_at = now()
+WHERE a.kind = 'system';
@@ -0,0 +1,7 @@
+-- A conta de sistema entra em todo lançamento; manter seu saldo em
+-- accounts.balance serializava os consumers num único lock. A partir
+-- daqui o saldo dela é sempre SUM(postings.amount).
+UPDATE public.accounts SET balance = 0, updated_at = now() WHERE kind = 'system';
+
+COMMENT ON COLUMN public.accounts.balance IS
+	'Saldo materializado das contas de usuário; contas de sistema usam a soma das postings';
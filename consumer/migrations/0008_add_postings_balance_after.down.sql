@@ -0,0 +1 @@
+ALTER TABLE public.postings DROP COLUMN IF EXISTS balance_after;
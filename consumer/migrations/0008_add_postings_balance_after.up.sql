@@ -0,0 +1,15 @@
+-- Saldo da conta logo após cada posting, gravado pelo consumer sob o lock
+-- da linha em accounts. O extrato lê esse valor em vez de somar todo o
+-- histórico a cada página. Fica NULL na conta de sistema, cujo saldo não
+-- é materializado (0007_derive_system_account_balance).
+ALTER TABLE public.postings ADD COLUMN IF NOT EXISTS balance_after NUMERIC(14,2);
+
+UPDATE public.postings p
+SET balance_after = s.running
+FROM (
+	SELECT p2.id, SUM(p2.amount) OVER (PARTITION BY p2.account_id ORDER BY p2.id) AS running
+	FROM public.postings p2
+	JOIN public.accounts a ON a.id = p2.account_id
+	WHERE a.kind = 'user'
+) s
+WHERE p.id = s.id;
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =========================================================
+// 🔏 Verificação de assinatura das mensagens SNS
+// =========================================================
+//
+// Sem verificação, qualquer um com permissão de SendMessage nas filas pode
+// injetar um envelope SNS forjado. Com SNS_VERIFY_SIGNATURES=true o consumer
+// confere a assinatura (SignatureVersion 1 = SHA1, 2 = SHA256) contra o
+// certificado do SNS e, opcionalmente, o tópico de origem.
+
+var (
+	errSignatureInvalid   = errors.New("assinatura SNS inválida")
+	errCertURLUntrusted   = errors.New("SigningCertURL não pertence ao SNS")
+	errTopicNotAllowed    = errors.New("tópico SNS não autorizado")
+	errUnsupportedSigning = errors.New("SignatureVersion não suportada")
+)
+
+// snsCertHostPattern cobre as regiões comerciais e da China.
+var snsCertHostPattern = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// snsMessage guarda os campos como texto: a assinatura é feita sobre os
+// valores originais (o Timestamp não pode passar por time.Time).
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	SubscribeURL     string `json:"SubscribeURL"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+}
+
+// stringToSign monta o texto assinado pelo SNS, conforme o tipo da mensagem.
+func (m snsMessage) stringToSign() string {
+	var fields []string
+	switch m.Type {
+	case "SubscriptionConfirmation", "UnsubscribeConfirmation":
+		fields = []string{
+			"Message", m.Message,
+			"MessageId", m.MessageID,
+			"SubscribeURL", m.SubscribeURL,
+			"Timestamp", m.Timestamp,
+			"Token", m.Token,
+			"TopicArn", m.TopicArn,
+			"Type", m.Type,
+		}
+	default:
+		fields = []string{"Message", m.Message, "MessageId", m.MessageID}
+		if m.Subject != "" {
+			fields = append(fields, "Subject", m.Subject)
+		}
+		fields = append(fields,
+			"Timestamp", m.Timestamp,
+			"TopicArn", m.TopicArn,
+			"Type", m.Type,
+		)
+	}
+	return strings.Join(fields, "\n") + "\n"
+}
+
+// =========================================================
+// 📜 Certificados
+// =========================================================
+
+// CertificateFetcher obtém o certificado de assinatura. Os testes usam uma
+// implementação com certificados gerados localmente.
+type CertificateFetcher interface {
+	FetchCertificate(ctx context.Context, certURL string) (*x509.Certificate, error)
+}
+
+type httpCertificateFetcher struct {
+	client *http.Client
+}
+
+func newHTTPCertificateFetcher() *httpCertificateFetcher {
+	return &httpCertificateFetcher{client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (f *httpCertificateFetcher) FetchCertificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao baixar certificado SNS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("erro ao baixar certificado SNS: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("certificado SNS não está em PEM")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// cachingCertificateFetcher evita baixar o certificado a cada mensagem; a
+// entrada é descartada quando o certificado expira.
+type cachingCertificateFetcher struct {
+	next  CertificateFetcher
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+func newCachingCertificateFetcher(next CertificateFetcher) *cachingCertificateFetcher {
+	return &cachingCertificateFetcher{next: next, certs: map[string]*x509.Certificate{}}
+}
+
+func (f *cachingCertificateFetcher) FetchCertificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	f.mu.Lock()
+	cert, ok := f.certs[certURL]
+	f.mu.Unlock()
+	if ok && time.Now().Before(cert.NotAfter) {
+		return cert, nil
+	}
+
+	cert, err := f.next.FetchCertificate(ctx, certURL)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.certs[certURL] = cert
+	f.mu.Unlock()
+	return cert, nil
+}
+
+// =========================================================
+// ✅ Verificador
+// =========================================================
+type snsSignatureVerifier struct {
+	fetcher       CertificateFetcher
+	allowedTopics map[string]bool
+}
+
+// signatureVerifier é nil quando a verificação está desligada.
+var signatureVerifier *snsSignatureVerifier
+
+// newSNSSignatureVerifier cria o verificador. Sem allowedTopics, qualquer
+// tópico com assinatura válida é aceito.
+func newSNSSignatureVerifier(fetcher CertificateFetcher, allowedTopics []string) *snsSignatureVerifier {
+	v := &snsSignatureVerifier{fetcher: fetcher, allowedTopics: map[string]bool{}}
+	for _, arn := range allowedTopics {
+		if arn = strings.TrimSpace(arn); arn != "" {
+			v.allowedTopics[arn] = true
+		}
+	}
+	return v
+}
+
+// signatureVerifierFromEnv lê SNS_VERIFY_SIGNATURES e SNS_ALLOWED_TOPIC_ARNS
+// (lista separada por vírgulas).
+func signatureVerifierFromEnv() *snsSignatureVerifier {
+	if os.Getenv("SNS_VERIFY_SIGNATURES") != "true" {
+		return nil
+	}
+	return newSNSSignatureVerifier(
+		newCachingCertificateFetcher(newHTTPCertificateFetcher()),
+		strings.Split(os.Getenv("SNS_ALLOWED_TOPIC_ARNS"), ","),
+	)
+}
+
+func validateCertURL(certURL string) error {
+	u, err := url.Parse(certURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errCertURLUntrusted, err)
+	}
+	if u.Scheme != "https" || !snsCertHostPattern.MatchString(u.Hostname()) || u.Port() != "" ||
+		!strings.HasSuffix(u.Path, ".pem") {
+		return fmt.Errorf("%w: %s", errCertURLUntrusted, certURL)
+	}
+	return nil
+}
+
+// Verify confere a assinatura do envelope SNS (corpo da mensagem SQS).
+func (v *snsSignatureVerifier) Verify(ctx context.Context, body []byte) error {
+	var msg snsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return fmt.Errorf("%w: %v", errSignatureInvalid, err)
+	}
+
+	if len(v.allowedTopics) > 0 && !v.allowedTopics[msg.TopicArn] {
+		return fmt.Errorf("%w: %s", errTopicNotAllowed, msg.TopicArn)
+	}
+
+	var (
+		hash   crypto.Hash
+		digest []byte
+	)
+	switch msg.SignatureVersion {
+	case "1":
+		sum := sha1.Sum([]byte(msg.stringToSign()))
+		hash, digest = crypto.SHA1, sum[:]
+	case "2":
+		sum := sha256.Sum256([]byte(msg.stringToSign()))
+		hash, digest = crypto.SHA256, sum[:]
+	default:
+		return fmt.Errorf("%w: %q", errUnsupportedSigning, msg.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("%w: assinatura ausente ou malformada", errSignatureInvalid)
+	}
+
+	if err := validateCertURL(msg.SigningCertURL); err != nil {
+		return err
+	}
+	cert, err := v.fetcher.FetchCertificate(ctx, msg.SigningCertURL)
+	if err != nil {
+		// Falha de rede ao buscar o certificado é temporária
+		return retryable(err)
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Errorf("%w: certificado fora da validade", errSignatureInvalid)
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: chave do certificado não é RSA", errSignatureInvalid)
+	}
+	if err := rsa.VerifyPKCS1v15(pub, hash, digest, signature); err != nil {
+		return fmt.Errorf("%w: %v", errSignatureInvalid, err)
+	}
+	return nil
+}
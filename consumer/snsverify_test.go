@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// =========================================================
+// 🔑 Certificado gerado localmente
+// =========================================================
+const (
+	testCertURL  = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-teste.pem"
+	testTopicARN = "arn:aws:sns:us-east-1:123456789012:finorbit-dev-transactions"
+)
+
+type testSigner struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newTestSigner(t *testing.T, notAfter time.Time) *testSigner {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Erro ao gerar chave: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Erro ao gerar certificado: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testSigner{key: key, cert: cert}
+}
+
+// signedBody monta um envelope SNS assinado na versão informada.
+func (s *testSigner) signedBody(t *testing.T, version string, msg snsMessage) string {
+	t.Helper()
+	msg.SignatureVersion = version
+	if msg.SigningCertURL == "" {
+		msg.SigningCertURL = testCertURL
+	}
+
+	var (
+		sig []byte
+		err error
+	)
+	switch version {
+	case "1":
+		sum := sha1.Sum([]byte(msg.stringToSign()))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA1, sum[:])
+	default:
+		sum := sha256.Sum256([]byte(msg.stringToSign()))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	}
+	if err != nil {
+		t.Fatalf("Erro ao assinar: %v", err)
+	}
+	msg.Signature = base64.StdEncoding.EncodeToString(sig)
+
+	body, _ := json.Marshal(msg)
+	return string(body)
+}
+
+type fakeCertificateFetcher struct {
+	cert  *x509.Certificate
+	err   error
+	calls int
+}
+
+func (f *fakeCertificateFetcher) FetchCertificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	f.calls++
+	return f.cert, f.err
+}
+
+func testNotification() snsMessage {
+	return snsMessage{
+		Type:      "Notification",
+		MessageID: "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:  testTopicARN,
+		Message:   `{"schema_version":1,"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11"}`,
+		Timestamp: "2025-11-07T12:00:00.120Z",
+	}
+}
+
+// =========================================================
+// ✅ Assinaturas válidas e inválidas
+// =========================================================
+func TestVerify_AssinaturaV1EV2(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	v := newSNSSignatureVerifier(&fakeCertificateFetcher{cert: signer.cert}, []string{testTopicARN})
+
+	for _, version := range []string{"1", "2"} {
+		body := signer.signedBody(t, version, testNotification())
+		if err := v.Verify(context.Background(), []byte(body)); err != nil {
+			t.Errorf("SignatureVersion %s: esperava assinatura válida, obteve %v", version, err)
+		}
+	}
+}
+
+func TestVerify_ComSubject(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	v := newSNSSignatureVerifier(&fakeCertificateFetcher{cert: signer.cert}, nil)
+
+	msg := testNotification()
+	msg.Subject = "Transação"
+	if err := v.Verify(context.Background(), []byte(signer.signedBody(t, "2", msg))); err != nil {
+		t.Errorf("Esperava assinatura válida com Subject, obteve %v", err)
+	}
+}
+
+func TestVerify_MensagemAdulterada(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	v := newSNSSignatureVerifier(&fakeCertificateFetcher{cert: signer.cert}, nil)
+
+	var msg snsMessage
+	_ = json.Unmarshal([]byte(signer.signedBody(t, "2", testNotification())), &msg)
+	msg.Message = `{"schema_version":1,"amount":"1000000"}`
+	body, _ := json.Marshal(msg)
+
+	if err := v.Verify(context.Background(), body); !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("Esperava errSignatureInvalid, obteve %v", err)
+	}
+}
+
+func TestVerify_CertificadoDeOutraChave(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	other := newTestSigner(t, time.Now().Add(time.Hour))
+	v := newSNSSignatureVerifier(&fakeCertificateFetcher{cert: other.cert}, nil)
+
+	if err := v.Verify(context.Background(), []byte(signer.signedBody(t, "1", testNotification()))); !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("Esperava errSignatureInvalid, obteve %v", err)
+	}
+}
+
+func TestVerify_CertificadoExpirado(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(-time.Minute))
+	v := newSNSSignatureVerifier(&fakeCertificateFetcher{cert: signer.cert}, nil)
+
+	if err := v.Verify(context.Background(), []byte(signer.signedBody(t, "2", testNotification()))); !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("Esperava errSignatureInvalid, obteve %v", err)
+	}
+}
+
+func TestVerify_URLDeCertificadoNaoConfiavel(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	fetcher := &fakeCertificateFetcher{cert: signer.cert}
+	v := newSNSSignatureVerifier(fetcher, nil)
+
+	urls := []string{
+		"http://sns.us-east-1.amazonaws.com/cert.pem",
+		"https://sns.us-east-1.amazonaws.com.evil.com/cert.pem",
+		"https://evil.com/sns.us-east-1.amazonaws.com/cert.pem",
+		"https://sns.us-east-1.amazonaws.com:8443/cert.pem",
+		"https://sns.us-east-1.amazonaws.com/cert.txt",
+	}
+	for _, u := range urls {
+		msg := testNotification()
+		msg.SigningCertURL = u
+		if err := v.Verify(context.Background(), []byte(signer.signedBody(t, "2", msg))); !errors.Is(err, errCertURLUntrusted) {
+			t.Errorf("%s: esperava errCertURLUntrusted, obteve %v", u, err)
+		}
+	}
+	if fetcher.calls != 0 {
+		t.Errorf("Certificado não deveria ser baixado de URL não confiável (%d chamadas)", fetcher.calls)
+	}
+}
+
+func TestVerify_TopicoNaoAutorizado(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	v := newSNSSignatureVerifier(&fakeCertificateFetcher{cert: signer.cert}, []string{"arn:aws:sns:us-east-1:123456789012:outro"})
+
+	if err := v.Verify(context.Background(), []byte(signer.signedBody(t, "2", testNotification()))); !errors.Is(err, errTopicNotAllowed) {
+		t.Errorf("Esperava errTopicNotAllowed, obteve %v", err)
+	}
+}
+
+func TestVerify_VersaoNaoSuportada(t *testing.T) {
+	v := newSNSSignatureVerifier(&fakeCertificateFetcher{}, nil)
+	msg := testNotification()
+	msg.SignatureVersion = "3"
+	body, _ := json.Marshal(msg)
+
+	if err := v.Verify(context.Background(), body); !errors.Is(err, errUnsupportedSigning) {
+		t.Errorf("Esperava errUnsupportedSigning, obteve %v", err)
+	}
+}
+
+// =========================================================
+// 📜 Busca e cache de certificados
+// =========================================================
+func TestCachingCertificateFetcher_ReusaCertificado(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	inner := &fakeCertificateFetcher{cert: signer.cert}
+	cache := newCachingCertificateFetcher(inner)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.FetchCertificate(context.Background(), testCertURL); err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("Esperava 1 download, obteve %d", inner.calls)
+	}
+}
+
+func TestCachingCertificateFetcher_RenovaExpirado(t *testing.T) {
+	expired := newTestSigner(t, time.Now().Add(-time.Minute))
+	inner := &fakeCertificateFetcher{cert: expired.cert}
+	cache := newCachingCertificateFetcher(inner)
+
+	_, _ = cache.FetchCertificate(context.Background(), testCertURL)
+	_, _ = cache.FetchCertificate(context.Background(), testCertURL)
+	if inner.calls != 2 {
+		t.Errorf("Esperava novo download para certificado expirado, obteve %d chamadas", inner.calls)
+	}
+}
+
+func TestHTTPCertificateFetcher_LePEM(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: signer.cert.Raw})
+	}))
+	defer srv.Close()
+
+	cert, err := newHTTPCertificateFetcher().FetchCertificate(context.Background(), srv.URL+"/cert.pem")
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if !cert.Equal(signer.cert) {
+		t.Error("Certificado lido difere do servido")
+	}
+}
+
+// =========================================================
+// 📬 Integração com o processamento
+// =========================================================
+func TestProcessRecord_EnvelopeSemAssinaturaEhPermanente(t *testing.T) {
+	signatureVerifier = newSNSSignatureVerifier(&fakeCertificateFetcher{}, nil)
+	t.Cleanup(func() { signatureVerifier = nil })
+
+	body, _ := snsEnvelope(`{"schema_version":1}`, nil)
+	err := processRecord(context.Background(), nil, events.SQSMessage{Body: body})
+	if !isPermanent(err) || !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("Esperava falha permanente de assinatura, obteve %v", err)
+	}
+}
+
+func TestProcessRecord_FalhaAoBuscarCertificadoEhTemporaria(t *testing.T) {
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	signatureVerifier = newSNSSignatureVerifier(&fakeCertificateFetcher{err: errors.New("timeout")}, nil)
+	t.Cleanup(func() { signatureVerifier = nil })
+
+	body := signer.signedBody(t, "2", testNotification())
+	err := processRecord(context.Background(), nil, events.SQSMessage{Body: body})
+	if err == nil || isPermanent(err) {
+		t.Errorf("Esperava falha temporária, obteve %v", err)
+	}
+}
+
+func TestProcessRecord_FalhaDeCertificadoNaoContaComoFalhaDoBanco(t *testing.T) {
+	rec := useRecordingAlerter(t)
+	signer := newTestSigner(t, time.Now().Add(time.Hour))
+	signatureVerifier = newSNSSignatureVerifier(&fakeCertificateFetcher{err: errors.New("timeout")}, nil)
+	t.Cleanup(func() { signatureVerifier = nil })
+
+	body := signer.signedBody(t, "2", testNotification())
+	for i := 0; i < dbFailureThreshold; i++ {
+		processRecord(context.Background(), nil, events.SQSMessage{Body: body})
+	}
+
+	if got := dbFailures.Fail(); got != 1 {
+		t.Errorf("Falha de certificado não deveria avançar a sequência do banco, próxima falha contou %d", got)
+	}
+	if len(rec.alerts) != 0 {
+		t.Errorf("Não deveria alertar sobre o banco, obteve %v", rec.kinds())
+	}
+}
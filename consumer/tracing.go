@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"finorbit/platform/tracing"
+)
+
+// =========================================================
+// 🔭 Tracing (OpenTelemetry)
+// =========================================================
+//
+// OTEL_TRACES_EXPORTER escolhe o exportador: "otlp" (OTLP/HTTP, configurado
+// pelas variáveis OTEL_EXPORTER_OTLP_*), "stdout" (execução local) ou
+// vazio/"none" para desligar. Cada mensagem continua o trace do producer a
+// partir do traceparent nos atributos, e cada comando SQL vira um span filho.
+const (
+	tracerName         = "finorbit/consumer"
+	defaultServiceName = "finorbit-consumer"
+)
+
+// Com o tracing desligado os spans são no-op e nada é propagado.
+var tracerProvider, propagator = tracing.Disabled()
+
+func tracer() trace.Tracer {
+	return tracerProvider.Tracer(tracerName)
+}
+
+// setupTracing liga o exportador de OTEL_TRACES_EXPORTER (ver
+// finorbit/platform/tracing).
+func setupTracing(ctx context.Context) error {
+	provider, prop, err := tracing.Setup(ctx, defaultServiceName)
+	if err != nil {
+		return err
+	}
+	tracerProvider, propagator = provider, prop
+	return nil
+}
+
+// startRecordSpan abre o span de processamento de uma mensagem. Se ela
+// trouxer traceparent, o span continua o trace do producer e aponta para o
+// span do lote por um link; senão fica como filho do lote. O traceparent
+// não é assinado pelo SNS (ver correlation.go): serve para navegar entre
+// traces, não para atestar a origem da mensagem.
+func startRecordSpan(ctx context.Context, record events.SQSMessage, attributes map[string]string) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.operation.type", "process"),
+			attribute.String("messaging.message.id", record.MessageId),
+		),
+	}
+
+	remote := trace.SpanContextFromContext(propagator.Extract(context.Background(), propagation.MapCarrier(attributes)))
+	if remote.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: trace.SpanContextFromContext(ctx)}))
+		ctx = trace.ContextWithRemoteSpanContext(ctx, remote)
+	}
+	return tracer().Start(ctx, "transactions process", opts...)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// =========================================================
+// 🔭 Tracing
+// =========================================================
+
+// useSpanRecorder liga o tracing com um gravador em memória durante o teste.
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := tracerProvider, propagator
+	tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	propagator = propagation.TraceContext{}
+	t.Cleanup(func() { tracerProvider, propagator = prevProvider, prevPropagator })
+	return recorder
+}
+
+func spansNamed(recorder *tracetest.SpanRecorder, name string) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+const producerTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTracing_ContinuaTraceDoProducer(t *testing.T) {
+	recorder := useSpanRecorder(t)
+
+	body, _ := snsEnvelope(`{"schema_version":2,"event_id":"e"}`, map[string]string{"traceparent": producerTraceparent})
+	handlerWithMockDB(t, events.SQSMessage{MessageId: "m-1", Body: body})
+
+	batch := spansNamed(recorder, "transactions process batch")
+	records := spansNamed(recorder, "transactions process")
+	if len(batch) != 1 || len(records) != 1 {
+		t.Fatalf("Esperava 1 span de lote e 1 de mensagem, obteve %d e %d", len(batch), len(records))
+	}
+
+	rec := records[0]
+	if rec.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		rec.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Span da mensagem deveria ser filho do span de publicação do producer")
+	}
+	if len(rec.Links()) != 1 || rec.Links()[0].SpanContext.SpanID() != batch[0].SpanContext().SpanID() {
+		t.Errorf("Esperava link para o span do lote, obteve %v", rec.Links())
+	}
+	if rec.Status().Code.String() != "Error" {
+		t.Errorf("Mensagem rejeitada deveria marcar o span com erro, obteve %v", rec.Status())
+	}
+}
+
+func TestTracing_SemTraceparentFicaSobOLote(t *testing.T) {
+	recorder := useSpanRecorder(t)
+
+	handlerWithMockDB(t, events.SQSMessage{MessageId: "m-1", Body: "mensagem inválida"})
+
+	batch := spansNamed(recorder, "transactions process batch")
+	records := spansNamed(recorder, "transactions process")
+	if len(batch) != 1 || len(records) != 1 {
+		t.Fatalf("Esperava 1 span de lote e 1 de mensagem, obteve %d e %d", len(batch), len(records))
+	}
+	if records[0].Parent().SpanID() != batch[0].SpanContext().SpanID() {
+		t.Errorf("Sem traceparent, o span da mensagem deveria ser filho do lote")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pborman/uuid"
+
+	txevents "finorbit/events"
+)
+
+// =========================================================
+// ⬆️ Upcasting de versões antigas do evento
+// =========================================================
+//
+// Mensagens publicadas com schemas anteriores podem continuar nas filas
+// (ou na DLQ) depois de um deploy. Cada upcaster converte o JSON de uma
+// versão para a seguinte; a cadeia é aplicada até chegar em
+// txevents.SchemaVersion. Versões futuras são recusadas como permanentes.
+
+// upcaster altera em memória os campos de um evento da versão N para N+1.
+type upcaster func(fields map[string]json.RawMessage, original []byte) error
+
+// upcasters é indexado pela versão de origem.
+var upcasters = map[int]upcaster{
+	txevents.LegacyVersion: upcastLegacyToV1,
+}
+
+// legacyEventNamespace gera event_ids determinísticos para eventos legados.
+var legacyEventNamespace = uuid.NewSHA1(uuid.NameSpace_URL, []byte("https://finorbit/legacy-event"))
+
+// upcastLegacyToV1: eventos anteriores à idempotência não tinham event_id.
+// O ID é derivado do conteúdo, então reentregas da mesma mensagem continuam
+// sendo descartadas como duplicadas.
+func upcastLegacyToV1(fields map[string]json.RawMessage, original []byte) error {
+	var eventID string
+	if raw, ok := fields["event_id"]; ok {
+		_ = json.Unmarshal(raw, &eventID)
+	}
+	if eventID == "" {
+		id, _ := json.Marshal(uuid.NewSHA1(legacyEventNamespace, original).String())
+		fields["event_id"] = id
+	}
+	return nil
+}
+
+// eventPayload devolve o JSON do evento, desembrulhando o `data` quando a
+// mensagem vem em CloudEvents (modo estruturado). ce é nil no formato puro.
+func eventPayload(message []byte) (payload []byte, ce *txevents.CloudEvent, err error) {
+	if !txevents.IsCloudEvent(message) {
+		return message, nil, nil
+	}
+	decoded, err := txevents.DecodeCloudEvent(message)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decoded.Data, &decoded, nil
+}
+
+// decodeTransaction lê o evento (puro ou CloudEvents) em qualquer versão
+// conhecida e devolve a forma atual.
+func decodeTransaction(message []byte) (Transaction, error) {
+	payload, ce, err := eventPayload(message)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx, err := upcastTransaction(payload)
+	if err != nil {
+		return tx, err
+	}
+	if ce == nil {
+		return tx, nil
+	}
+	// O envelope precisa descrever o próprio payload: um type divergente
+	// faria o evento ser roteado (filtro do SNS) como uma operação e
+	// lançado como outra
+	if ce.ID != tx.EventID {
+		return tx, fmt.Errorf("%w: id %q difere do event_id %q", txevents.ErrInvalidCloudEvent, ce.ID, tx.EventID)
+	}
+	if want := txevents.CloudEventType(tx.Type); ce.Type != want {
+		return tx, fmt.Errorf("%w: type %q difere do esperado %q", txevents.ErrInvalidCloudEvent, ce.Type, want)
+	}
+	return tx, nil
+}
+
+// upcastTransaction aplica a cadeia de upcasters até a versão atual.
+func upcastTransaction(message []byte) (Transaction, error) {
+	version, err := txevents.Version(message)
+	if err != nil {
+		return Transaction{}, err
+	}
+	if version > txevents.SchemaVersion {
+		return Transaction{}, fmt.Errorf("%w: %d (suportada até %d)",
+			txevents.ErrUnsupportedVersion, version, txevents.SchemaVersion)
+	}
+	if version == txevents.SchemaVersion {
+		return txevents.Decode(message)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(message, &fields); err != nil {
+		return Transaction{}, err
+	}
+
+	for ; version < txevents.SchemaVersion; version++ {
+		up, ok := upcasters[version]
+		if !ok {
+			return Transaction{}, fmt.Errorf("%w: sem upcaster para a versão %d",
+				txevents.ErrUnsupportedVersion, version)
+		}
+		if err := up(fields, message); err != nil {
+			return Transaction{}, fmt.Errorf("erro ao converter versão %d: %w", version, err)
+		}
+		fields["schema_version"], _ = json.Marshal(version + 1)
+	}
+
+	upgraded, err := json.Marshal(fields)
+	if err != nil {
+		return Transaction{}, err
+	}
+	return txevents.Decode(upgraded)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+
+	txevents "finorbit/events"
+)
+
+// =========================================================
+// ⬆️ Upcasting
+// =========================================================
+const legacyEvent = `{"user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"100.00","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`
+
+func TestDecodeTransaction_LegadoGanhaEventIDDeterministico(t *testing.T) {
+	first, err := decodeTransaction([]byte(legacyEvent))
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	second, _ := decodeTransaction([]byte(legacyEvent))
+
+	if first.SchemaVersion != txevents.SchemaVersion {
+		t.Errorf("Esperava versão %d após upcast, obteve %d", txevents.SchemaVersion, first.SchemaVersion)
+	}
+	if first.EventID == "" || first.EventID != second.EventID {
+		t.Errorf("Esperava event_id derivado e estável, obteve %q e %q", first.EventID, second.EventID)
+	}
+	if first.UserID != "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b" || first.Amount.String() != "100" {
+		t.Errorf("Campos perdidos no upcast: %+v", first)
+	}
+}
+
+func TestDecodeTransaction_LegadoComEventIDPreservaID(t *testing.T) {
+	tx, err := decodeTransaction([]byte(`{"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"1","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`))
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if tx.EventID != "3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11" {
+		t.Errorf("event_id original deveria ser mantido, obteve %q", tx.EventID)
+	}
+}
+
+func TestDecodeTransaction_VersaoAtualSemUpcast(t *testing.T) {
+	tx, err := decodeTransaction([]byte(`{"schema_version":1,"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"1","type":"withdraw","timestamp":"2025-11-07T00:00:00Z"}`))
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if tx.EventID != "3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11" || tx.Type != "withdraw" {
+		t.Errorf("Evento decodificado incorretamente: %+v", tx)
+	}
+}
+
+func TestDecodeTransaction_VersaoFuturaVaiParaDLQ(t *testing.T) {
+	_, err := decodeTransaction([]byte(`{"schema_version":2,"event_id":"e"}`))
+	if !errors.Is(err, txevents.ErrUnsupportedVersion) {
+		t.Fatalf("Esperava ErrUnsupportedVersion, obteve %v", err)
+	}
+
+	body, _ := snsEnvelope(`{"schema_version":2,"event_id":"e"}`, nil)
+	resp, _ := handlerWithMockDB(t, events.SQSMessage{MessageId: "m-1", Body: body})
+	if len(resp.BatchItemFailures) != 1 {
+		t.Errorf("Esperava a mensagem em BatchItemFailures (rumo à DLQ), obteve %v", resp.BatchItemFailures)
+	}
+}
+
+func TestDecodeTransaction_SemUpcasterNaCadeia(t *testing.T) {
+	saved := upcasters
+	upcasters = map[int]upcaster{}
+	t.Cleanup(func() { upcasters = saved })
+
+	if _, err := decodeTransaction([]byte(legacyEvent)); !errors.Is(err, txevents.ErrUnsupportedVersion) {
+		t.Errorf("Esperava ErrUnsupportedVersion sem upcaster, obteve %v", err)
+	}
+}
+
+// handlerWithMockDB executa o handler com um banco mockado sem expectativas.
+func handlerWithMockDB(t *testing.T, records ...events.SQSMessage) (events.SQSEventResponse, error) {
+	t.Helper()
+	resetDBSingleton()
+	dbMock, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar mock: %v", err)
+	}
+	t.Cleanup(func() { dbMock.Close() })
+	db = dbMock
+	return handler(context.Background(), events.SQSEvent{Records: records})
+}
+
+// =========================================================
+// ☁️ CloudEvents
+// =========================================================
+func TestDecodeTransaction_CloudEvent(t *testing.T) {
+	ce := `{"specversion":"1.0","id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","source":"urn:teste","type":"finorbit.transaction.deposit","data":{"schema_version":1,"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"5","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}}`
+	tx, err := decodeTransaction([]byte(ce))
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if tx.EventID != "3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11" || tx.Amount.String() != "5" {
+		t.Errorf("Evento decodificado incorretamente: %+v", tx)
+	}
+}
+
+func TestDecodeTransaction_CloudEventIDDivergente(t *testing.T) {
+	ce := `{"specversion":"1.0","id":"outro","source":"urn:teste","type":"finorbit.transaction.deposit","data":{"schema_version":1,"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"5","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}}`
+	if _, err := decodeTransaction([]byte(ce)); !errors.Is(err, txevents.ErrInvalidCloudEvent) {
+		t.Errorf("Esperava ErrInvalidCloudEvent, obteve %v", err)
+	}
+}
+
+func TestDecodeTransaction_CloudEventTypeDivergente(t *testing.T) {
+	ce := `{"specversion":"1.0","id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","source":"urn:teste","type":"finorbit.transaction.withdraw","data":{"schema_version":1,"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"5","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}}`
+	tx, err := decodeTransaction([]byte(ce))
+	if !errors.Is(err, txevents.ErrInvalidCloudEvent) {
+		t.Fatalf("Esperava ErrInvalidCloudEvent, obteve %v", err)
+	}
+	// O event_id segue disponível para registrar a falha
+	if tx.EventID != "3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11" {
+		t.Errorf("Esperava o event_id do payload, obteve %q", tx.EventID)
+	}
+}
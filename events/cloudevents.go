@@ -0,0 +1,97 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// =========================================================
+// ☁️ CloudEvents 1.0 (modo estruturado)
+// =========================================================
+//
+// Alternativa ao JSON "puro" para assinantes externos: o evento de
+// transação vai em `data` e os metadados seguem a especificação
+// (https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md).
+
+const (
+	CloudEventsSpecVersion  = "1.0"
+	CloudEventsContentType  = "application/cloudevents+json"
+	DefaultCloudEventSource = "urn:finorbit:producer"
+
+	cloudEventTypePrefix = "finorbit.transaction."
+)
+
+var ErrInvalidCloudEvent = errors.New("CloudEvent inválido")
+
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// CloudEventType devolve o tipo publicado, ex.: finorbit.transaction.deposit.
+func CloudEventType(transactionType string) string {
+	return cloudEventTypePrefix + transactionType
+}
+
+// DataSchemaURI identifica a versão do schema de `data`.
+func DataSchemaURI(version int) string {
+	return fmt.Sprintf("urn:finorbit:schema:transaction:v%d", version)
+}
+
+// EncodeCloudEvent valida o evento e o embrulha num CloudEvent estruturado.
+func EncodeCloudEvent(e TransactionEvent, source string) ([]byte, error) {
+	data, err := Encode(e)
+	if err != nil {
+		return nil, err
+	}
+	if source == "" {
+		source = DefaultCloudEventSource
+	}
+
+	return json.Marshal(CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              e.EventID,
+		Source:          source,
+		Type:            CloudEventType(e.Type),
+		Time:            e.Timestamp,
+		DataContentType: "application/json",
+		DataSchema:      DataSchemaURI(SchemaVersion),
+		Data:            data,
+	})
+}
+
+// IsCloudEvent detecta o modo estruturado pela presença de `specversion`.
+func IsCloudEvent(data []byte) bool {
+	var header struct {
+		SpecVersion *string `json:"specversion"`
+	}
+	return json.Unmarshal(data, &header) == nil && header.SpecVersion != nil
+}
+
+// DecodeCloudEvent lê o envelope e confere os atributos obrigatórios. O
+// conteúdo de `data` é devolvido cru, para passar pelo upcasting.
+func DecodeCloudEvent(data []byte) (CloudEvent, error) {
+	var ce CloudEvent
+	if err := json.Unmarshal(data, &ce); err != nil {
+		return ce, err
+	}
+
+	switch {
+	case ce.SpecVersion != CloudEventsSpecVersion:
+		return ce, fmt.Errorf("%w: specversion %q", ErrInvalidCloudEvent, ce.SpecVersion)
+	case ce.ID == "" || ce.Source == "" || ce.Type == "":
+		return ce, fmt.Errorf("%w: id, source e type são obrigatórios", ErrInvalidCloudEvent)
+	case ce.DataContentType != "" && ce.DataContentType != "application/json":
+		return ce, fmt.Errorf("%w: datacontenttype %q", ErrInvalidCloudEvent, ce.DataContentType)
+	case len(ce.Data) == 0:
+		return ce, fmt.Errorf("%w: data ausente", ErrInvalidCloudEvent)
+	}
+	return ce, nil
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// =========================================================
+// ☁️ CloudEvents — contrato em testdata/transaction_cloudevent_v1.json
+// =========================================================
+func TestEncodeCloudEvent_GeraContratoV1(t *testing.T) {
+	golden, err := os.ReadFile("testdata/transaction_cloudevent_v1.json")
+	if err != nil {
+		t.Fatalf("Erro ao ler contrato: %v", err)
+	}
+
+	data, err := EncodeCloudEvent(validEvent(), "")
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if string(data) != strings.TrimSpace(string(golden)) {
+		t.Errorf("CloudEvent divergente do contrato:\n obtido:   %s\n esperado: %s", data, golden)
+	}
+}
+
+func TestDecodeCloudEvent_IdaEVolta(t *testing.T) {
+	data, _ := EncodeCloudEvent(validEvent(), "urn:teste")
+	if !IsCloudEvent(data) {
+		t.Fatal("Esperava detectar o modo estruturado")
+	}
+
+	ce, err := DecodeCloudEvent(data)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if ce.Source != "urn:teste" || ce.Type != "finorbit.transaction.deposit" {
+		t.Errorf("Atributos incorretos: %+v", ce)
+	}
+
+	e, err := Decode(ce.Data)
+	if err != nil || e.EventID != ce.ID {
+		t.Errorf("data não corresponde ao evento: %+v, %v", e, err)
+	}
+}
+
+func TestIsCloudEvent_EventoPuro(t *testing.T) {
+	data, _ := Encode(validEvent())
+	if IsCloudEvent(data) {
+		t.Error("Evento puro não deveria ser detectado como CloudEvent")
+	}
+}
+
+func TestDecodeCloudEvent_Invalidos(t *testing.T) {
+	cases := []string{
+		`{"specversion":"0.3","id":"1","source":"s","type":"t","data":{}}`,
+		`{"specversion":"1.0","source":"s","type":"t","data":{}}`,
+		`{"specversion":"1.0","id":"1","source":"s","type":"t","datacontenttype":"text/xml","data":"<x/>"}`,
+		`{"specversion":"1.0","id":"1","source":"s","type":"t"}`,
+	}
+	for _, c := range cases {
+		if _, err := DecodeCloudEvent([]byte(c)); !errors.Is(err, ErrInvalidCloudEvent) {
+			t.Errorf("Esperava ErrInvalidCloudEvent para %s, obteve %v", c, err)
+		}
+	}
+}
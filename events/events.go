@@ -0,0 +1,157 @@
+// Package events define o contrato dos eventos trocados entre o producer e
+// o consumer. Qualquer mudança no formato passa por aqui e pela versão do
+// schema, nunca por structs duplicadas em cada serviço.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// =========================================================
+// 📜 Versão do schema
+// =========================================================
+// SchemaVersion é a versão emitida pelo producer e a única aceita por
+// Decode. Versões anteriores precisam ser convertidas (upcast) pelo consumer.
+const SchemaVersion = 1
+
+// LegacyVersion identifica os eventos publicados antes do versionamento,
+// que chegam sem o campo schema_version.
+const LegacyVersion = 0
+
+// =========================================================
+// 💡 Tipos de transação
+// =========================================================
+const (
+	TypeDeposit  = "deposit"
+	TypeWithdraw = "withdraw"
+)
+
+// IsValidType informa se o tipo é aceito pelo ledger.
+func IsValidType(t string) bool {
+	return t == TypeDeposit || t == TypeWithdraw
+}
+
+// =========================================================
+// 📨 Evento de transação
+// =========================================================
+// TransactionEvent é o payload publicado pelo producer e gravado pelo
+// consumer. UserID carrega o ID da conta; o nome `user_id` no JSON é
+// mantido por compatibilidade com as mensagens já em trânsito.
+type TransactionEvent struct {
+	SchemaVersion int             `json:"schema_version"`
+	EventID       string          `json:"event_id"`
+	UserID        string          `json:"user_id"`
+	Amount        decimal.Decimal `json:"amount"`
+	Type          string          `json:"type"`
+	Timestamp     string          `json:"timestamp"`
+}
+
+var (
+	ErrUnsupportedVersion = errors.New("versão de schema não suportada")
+	ErrInvalidEvent       = errors.New("evento inválido")
+	ErrAmountTooLarge     = errors.New("amount acima do máximo")
+)
+
+// AmountScale é o número de casas decimais gravado pelo ledger
+// (NUMERIC(12,2)); valores mais precisos seriam arredondados pelo Postgres.
+const AmountScale = 2
+
+// HasValidScale informa se o valor cabe em AmountScale casas decimais sem
+// arredondamento — "10.50" e "10.500" passam, "10.005" não.
+func HasValidScale(amount decimal.Decimal) bool {
+	return amount.Equal(amount.Truncate(AmountScale))
+}
+
+// MaxAmount é o maior valor que cabe em NUMERIC(12,2): 10 dígitos inteiros
+// e AmountScale casas. Acima disso o INSERT do ledger falharia por overflow.
+var MaxAmount = decimal.RequireFromString("9999999999.99")
+
+// IsWithinMaxAmount informa se o valor, em módulo, não passa de MaxAmount.
+func IsWithinMaxAmount(amount decimal.Decimal) bool {
+	return amount.Abs().LessThanOrEqual(MaxAmount)
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Validate confere os campos obrigatórios e reporta todos os problemas de
+// uma vez. Os erros embrulham ErrInvalidEvent.
+func (e TransactionEvent) Validate() error {
+	var problems []error
+
+	if !uuidPattern.MatchString(e.EventID) {
+		problems = append(problems, errors.New("event_id deve ser um UUID"))
+	}
+	if !uuidPattern.MatchString(e.UserID) {
+		problems = append(problems, errors.New("user_id deve ser um UUID"))
+	}
+	if !e.Amount.IsPositive() {
+		problems = append(problems, errors.New("amount deve ser maior que zero"))
+	}
+	if !HasValidScale(e.Amount) {
+		problems = append(problems, fmt.Errorf("amount deve ter no máximo %d casas decimais", AmountScale))
+	}
+	if !IsWithinMaxAmount(e.Amount) {
+		problems = append(problems, fmt.Errorf("%w (%s)", ErrAmountTooLarge, MaxAmount))
+	}
+	if !IsValidType(e.Type) {
+		problems = append(problems, fmt.Errorf("type desconhecido: %q", e.Type))
+	}
+	if _, err := time.Parse(time.RFC3339, e.Timestamp); err != nil {
+		problems = append(problems, errors.New("timestamp deve estar em RFC3339"))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidEvent, errors.Join(problems...))
+	}
+	return nil
+}
+
+// =========================================================
+// 🔤 Codificação JSON
+// =========================================================
+
+// Encode valida o evento e gera o JSON publicado, já com a versão atual
+// do schema.
+func Encode(e TransactionEvent) ([]byte, error) {
+	e.SchemaVersion = SchemaVersion
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// Version lê apenas o schema_version do JSON, sem decodificar o resto.
+// Eventos sem o campo são LegacyVersion.
+func Version(data []byte) (int, error) {
+	var header struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	if header.SchemaVersion == nil {
+		return LegacyVersion, nil
+	}
+	return *header.SchemaVersion, nil
+}
+
+// Decode lê o JSON de um evento na versão atual do schema e o valida com
+// as mesmas regras de Encode: o que o ledger não consegue gravar fielmente
+// é recusado aqui, e não arredondado pelo banco.
+func Decode(data []byte) (TransactionEvent, error) {
+	var e TransactionEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return e, err
+	}
+
+	if e.SchemaVersion != SchemaVersion {
+		return e, fmt.Errorf("%w: %d (esperada %d)", ErrUnsupportedVersion, e.SchemaVersion, SchemaVersion)
+	}
+	return e, e.Validate()
+}
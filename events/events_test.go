@@ -0,0 +1,140 @@
+package events
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func validEvent() TransactionEvent {
+	return TransactionEvent{
+		EventID:   "3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11",
+		UserID:    "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b",
+		Amount:    decimal.RequireFromString("150.50"),
+		Type:      TypeDeposit,
+		Timestamp: "2025-11-07T12:00:00Z",
+	}
+}
+
+// =========================================================
+// 📜 Contrato — o JSON de referência é usado também pelos
+// testes do producer e do consumer
+// =========================================================
+func TestEncode_GeraContratoV1(t *testing.T) {
+	golden, err := os.ReadFile("testdata/transaction_event_v1.json")
+	if err != nil {
+		t.Fatalf("Erro ao ler contrato: %v", err)
+	}
+
+	data, err := Encode(validEvent())
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if string(data) != strings.TrimSpace(string(golden)) {
+		t.Errorf("JSON divergente do contrato:\n obtido:   %s\n esperado: %s", data, golden)
+	}
+}
+
+func TestEncodeDecode_IdaEVolta(t *testing.T) {
+	data, err := Encode(validEvent())
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	want := validEvent()
+	if got.SchemaVersion != SchemaVersion || got.EventID != want.EventID || !got.Amount.Equal(want.Amount) {
+		t.Errorf("Evento divergente após ida e volta: %+v", got)
+	}
+}
+
+func TestDecode_ExigeVersaoAtual(t *testing.T) {
+	_, err := Decode([]byte(`{"event_id":"x","user_id":"y","amount":"1","type":"deposit"}`))
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Esperava ErrUnsupportedVersion para evento sem versão, obteve %v", err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	cases := map[string]int{
+		`{"event_id":"x"}`:                  LegacyVersion,
+		`{"schema_version":1}`:              1,
+		`{"schema_version":7,"amount":"1"}`: 7,
+	}
+	for data, want := range cases {
+		got, err := Version([]byte(data))
+		if err != nil || got != want {
+			t.Errorf("Version(%s) = %d, %v; esperava %d", data, got, err, want)
+		}
+	}
+	if _, err := Version([]byte(`{`)); err == nil {
+		t.Error("Esperava erro para JSON inválido")
+	}
+}
+
+func TestDecode_VersaoFutura(t *testing.T) {
+	_, err := Decode([]byte(`{"schema_version":99,"event_id":"x"}`))
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Esperava ErrUnsupportedVersion, obteve %v", err)
+	}
+}
+
+// =========================================================
+// ✅ Validação
+// =========================================================
+func TestValidate_ReportaTodosOsCampos(t *testing.T) {
+	err := TransactionEvent{Amount: decimal.Zero, Type: "transfer"}.Validate()
+	if !errors.Is(err, ErrInvalidEvent) {
+		t.Fatalf("Esperava ErrInvalidEvent, obteve %v", err)
+	}
+	for _, field := range []string{"event_id", "user_id", "amount", "type", "timestamp"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Erro não menciona %s: %v", field, err)
+		}
+	}
+}
+
+func TestEncode_RecusaEventoInvalido(t *testing.T) {
+	e := validEvent()
+	e.Amount = decimal.RequireFromString("-1")
+	if _, err := Encode(e); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Esperava ErrInvalidEvent, obteve %v", err)
+	}
+}
+
+func TestHasValidScale(t *testing.T) {
+	cases := map[string]bool{"10": true, "10.5": true, "10.50": true, "10.500": true, "10.005": false, "0.001": false}
+	for raw, want := range cases {
+		if got := HasValidScale(decimal.RequireFromString(raw)); got != want {
+			t.Errorf("HasValidScale(%s) = %v, esperava %v", raw, got, want)
+		}
+	}
+}
+
+func TestValidate_ValorMaximo(t *testing.T) {
+	e := validEvent()
+	e.Amount = MaxAmount
+	if err := e.Validate(); err != nil {
+		t.Errorf("MaxAmount deveria ser aceito, obteve %v", err)
+	}
+
+	e.Amount = MaxAmount.Add(decimal.RequireFromString("0.01"))
+	err := e.Validate()
+	if !errors.Is(err, ErrInvalidEvent) || !errors.Is(err, ErrAmountTooLarge) {
+		t.Errorf("Esperava ErrAmountTooLarge para %s, obteve %v", e.Amount, err)
+	}
+}
+
+func TestDecode_ValidaEvento(t *testing.T) {
+	data := `{"schema_version":1,"event_id":"3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11","user_id":"6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b","amount":"10.005","type":"deposit","timestamp":"2025-11-07T00:00:00Z"}`
+
+	if _, err := Decode([]byte(data)); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Esperava ErrInvalidEvent para valor com 3 casas, obteve %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+module finorbit/events
+
+go 1.25.0
+
+require github.com/shopspring/decimal v1.4.0
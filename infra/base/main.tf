@@ -47,12 +47,29 @@ resource "aws_sns_topic" "alerts" {
   name = "${local.name_prefix}-alerts"
 }
 
+# Mensagens que falham repetidamente (ex.: payload venenoso) vão para a DLQ
+resource "aws_sqs_queue" "transactions_deposit_dlq" {
+  name = "${local.name_prefix}-deposit-dlq"
+}
+
+resource "aws_sqs_queue" "transactions_withdraw_dlq" {
+  name = "${local.name_prefix}-withdraw-dlq"
+}
+
 resource "aws_sqs_queue" "transactions_deposit_queue" {
-  name = "${local.name_prefix}-deposit-queue"
+  name           = "${local.name_prefix}-deposit-queue"
+  redrive_policy = jsonencode({
+    deadLetterTargetArn = aws_sqs_queue.transactions_deposit_dlq.arn
+    maxReceiveCount     = var.max_receive_count
+  })
 }
 
 resource "aws_sqs_queue" "transactions_withdraw_queue" {
-  name = "${local.name_prefix}-withdraw-queue"
+  name           = "${local.name_prefix}-withdraw-queue"
+  redrive_policy = jsonencode({
+    deadLetterTargetArn = aws_sqs_queue.transactions_withdraw_dlq.arn
+    maxReceiveCount     = var.max_receive_count
+  })
 }
 
 resource "aws_sns_topic_subscription" "sns_to_sqs" {
@@ -86,6 +103,111 @@ resource "aws_sqs_queue_policy" "allow_sns_publish" {
   })
 }
 
+# =======================
+# 📤 Outbox (DynamoDB)
+# =======================
+# Eventos aceitos pelo producer ficam aqui até serem publicados no SNS
+resource "aws_dynamodb_table" "outbox" {
+  name         = "${local.name_prefix}-outbox"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "event_id"
+
+  attribute {
+    name = "event_id"
+    type = "S"
+  }
+
+  attribute {
+    name = "status"
+    type = "S"
+  }
+
+  attribute {
+    name = "next_attempt_at"
+    type = "N"
+  }
+
+  # Usado pelo relay para buscar as pendentes já vencidas
+  global_secondary_index {
+    name            = "status-next_attempt_at-index"
+    hash_key        = "status"
+    range_key       = "next_attempt_at"
+    projection_type = "ALL"
+  }
+
+  # Entradas enviadas expiram após alguns dias
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+}
+
+# A query (na VPC, sem NAT) lê o outbox para responder "accepted"
+data "aws_route_tables" "default" {
+  vpc_id = data.aws_vpc.default.id
+}
+
+resource "aws_vpc_endpoint" "dynamodb" {
+  vpc_id            = data.aws_vpc.default.id
+  service_name      = "com.amazonaws.${var.region}.dynamodb"
+  vpc_endpoint_type = "Gateway"
+  route_table_ids   = data.aws_route_tables.default.ids
+}
+
+resource "aws_iam_role_policy" "lambda_outbox" {
+  name = "${local.name_prefix}-lambda-outbox"
+  role = aws_iam_role.lambda_role.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect   = "Allow"
+      Action   = ["dynamodb:PutItem", "dynamodb:UpdateItem", "dynamodb:Query", "dynamodb:GetItem"]
+      Resource = [aws_dynamodb_table.outbox.arn, "${aws_dynamodb_table.outbox.arn}/index/*"]
+    }]
+  })
+}
+
+# Producer e consumers publicam alertas operacionais no tópico de alertas
+resource "aws_iam_role_policy" "lambda_alerts" {
+  name = "${local.name_prefix}-lambda-alerts"
+  role = aws_iam_role.lambda_role.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect   = "Allow"
+      Action   = "sns:Publish"
+      Resource = aws_sns_topic.alerts.arn
+    }]
+  })
+}
+
+# Consumers e query rodam nas subnets da VPC default, sem NAT: o segredo do
+# banco e os alertas saem por endpoints de interface, com DNS privado para
+# os clients do SDK não precisarem de configuração
+resource "aws_security_group" "vpc_endpoints" {
+  name        = "${local.name_prefix}-vpc-endpoints"
+  description = "HTTPS das Lambdas para os endpoints de interface"
+  vpc_id      = data.aws_vpc.default.id
+
+  ingress {
+    description     = "HTTPS a partir do SG das Lambdas"
+    from_port       = 443
+    to_port         = 443
+    protocol        = "tcp"
+    security_groups = [data.aws_security_group.default.id]
+  }
+}
+
+resource "aws_vpc_endpoint" "interface" {
+  for_each            = toset(["secretsmanager", "sns"])
+  vpc_id              = data.aws_vpc.default.id
+  service_name        = "com.amazonaws.${var.region}.${each.key}"
+  vpc_endpoint_type   = "Interface"
+  subnet_ids          = data.aws_subnets.private.ids
+  security_group_ids  = [aws_security_group.vpc_endpoints.id]
+  private_dns_enabled = true
+}
+
 # =======================
 # 📦 ECR
 # =======================
@@ -99,10 +221,16 @@ resource "aws_ecr_repository" "producer_repo" {
   force_delete = true
 }
 
+resource "aws_ecr_repository" "query_repo" {
+  name         = "${local.name_prefix}-query"
+  force_delete = true
+}
+
 resource "aws_ecr_repository_policy" "lambda_access" {
   for_each = {
     consumer = aws_ecr_repository.consumer_repo.name
     producer = aws_ecr_repository.producer_repo.name
+    query    = aws_ecr_repository.query_repo.name
   }
   repository = each.value
   policy = jsonencode({
@@ -147,9 +275,86 @@ resource "aws_db_instance" "finorbit_db" {
   instance_class          = "db.t3.micro"
   allocated_storage       = 20
   username                = "finorbit_admin"
-  password                = "Finorbit123!"
+  password                = random_password.db.result
   db_name                 = "finorbit"
   publicly_accessible     = true
   skip_final_snapshot     = true
   vpc_security_group_ids  = [data.aws_security_group.default.id]
 }
+
+# =======================
+# 🔑 Credenciais do banco (Secrets Manager)
+# =======================
+resource "random_password" "db" {
+  length           = 32
+  override_special = "!#$%^&*()-_=+[]{}<>:?"
+}
+
+# JSON no formato dos segredos do RDS, lido pelo consumer e pela query
+# (DB_CREDENTIALS_SOURCE=secretsmanager)
+resource "aws_secretsmanager_secret" "db_credentials" {
+  count = var.create_rds ? 1 : 0
+  name  = "${local.name_prefix}-db-credentials"
+}
+
+resource "aws_secretsmanager_secret_version" "db_credentials" {
+  count     = var.create_rds ? 1 : 0
+  secret_id = aws_secretsmanager_secret.db_credentials[0].id
+  secret_string = jsonencode({
+    engine   = "postgres"
+    host     = aws_db_instance.finorbit_db[0].address
+    port     = aws_db_instance.finorbit_db[0].port
+    username = aws_db_instance.finorbit_db[0].username
+    password = random_password.db.result
+    dbname   = aws_db_instance.finorbit_db[0].db_name
+  })
+
+  # A rotação grava novas versões; o Terraform não as desfaz
+  lifecycle {
+    ignore_changes = [secret_string]
+  }
+}
+
+# Rotação automática da senha (Lambda oficial do Serverless Application
+# Repository, usuário único). Os serviços relêem o segredo quando o
+# Postgres recusa a senha antiga, sem redeploy.
+resource "aws_serverlessapplicationrepository_cloudformation_stack" "db_rotation" {
+  count          = var.create_rds ? 1 : 0
+  name           = "${local.name_prefix}-db-rotation"
+  application_id = "arn:aws:serverlessrepo:us-east-1:297356227824:applications/SecretsManagerRDSPostgreSQLRotationSingleUser"
+  capabilities   = ["CAPABILITY_IAM", "CAPABILITY_RESOURCE_POLICY"]
+
+  parameters = {
+    functionName        = "${local.name_prefix}-db-rotation"
+    endpoint            = "https://secretsmanager.${var.region}.amazonaws.com"
+    vpcSubnetIds        = join(",", data.aws_subnets.private.ids)
+    vpcSecurityGroupIds = data.aws_security_group.default.id
+  }
+}
+
+resource "aws_secretsmanager_secret_rotation" "db_credentials" {
+  count               = var.create_rds ? 1 : 0
+  secret_id           = aws_secretsmanager_secret.db_credentials[0].id
+  rotation_lambda_arn = aws_serverlessapplicationrepository_cloudformation_stack.db_rotation[0].outputs["RotationLambdaARN"]
+
+  rotation_rules {
+    automatically_after_days = var.db_rotation_days
+  }
+
+  # A rotação precisa do endpoint do Secrets Manager dentro da VPC
+  depends_on = [aws_vpc_endpoint.interface]
+}
+
+resource "aws_iam_role_policy" "lambda_db_credentials" {
+  count = var.create_rds ? 1 : 0
+  name  = "${local.name_prefix}-lambda-db-credentials"
+  role  = aws_iam_role.lambda_role.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect   = "Allow"
+      Action   = "secretsmanager:GetSecretValue"
+      Resource = aws_secretsmanager_secret.db_credentials[0].arn
+    }]
+  })
+}
@@ -12,6 +12,10 @@ output "ecr_producer_repo_url" {
   value = aws_ecr_repository.producer_repo.repository_url
 }
 
+output "ecr_query_repo_url" {
+  value = aws_ecr_repository.query_repo.repository_url
+}
+
 # SQS Queues
 output "sqs_deposit_arn" {
   value = aws_sqs_queue.transactions_deposit_queue.arn
@@ -21,12 +25,31 @@ output "sqs_withdraw_arn" {
   value = aws_sqs_queue.transactions_withdraw_queue.arn
 }
 
+output "sqs_deposit_dlq_arn" {
+  value = aws_sqs_queue.transactions_deposit_dlq.arn
+}
+
+output "sqs_withdraw_dlq_arn" {
+  value = aws_sqs_queue.transactions_withdraw_dlq.arn
+}
+
 # SNS Topic
 output "sns_topic_arn" {
   value       = aws_sns_topic.transactions.arn
   description = "ARN do tópico SNS de transações"
 }
 
+output "alerts_topic_arn" {
+  value       = aws_sns_topic.alerts.arn
+  description = "ARN do tópico SNS de alertas operacionais"
+}
+
+# Outbox
+output "outbox_table_name" {
+  value       = aws_dynamodb_table.outbox.name
+  description = "Tabela DynamoDB do outbox do producer"
+}
+
 # =======================
 # 📦 RDS OUTPUTS
 # =======================
@@ -46,12 +69,19 @@ output "db_name" {
   description = "RDS database name"
 }
 
+# Senha inicial do RDS; após a primeira rotação só o segredo está em dia
+# (os serviços leem db_secret_arn)
 output "db_pass" {
-  value       = "Finorbit123!"
+  value       = random_password.db.result
   sensitive   = true
   description = "RDS password"
 }
 
+output "db_secret_arn" {
+  value       = try(aws_secretsmanager_secret.db_credentials[0].arn, null)
+  description = "Segredo com as credenciais do RDS"
+}
+
 output "vpc_id" {
   value = data.aws_vpc.default.id
 }
@@ -3,11 +3,23 @@ variable "env" {
   default = "dev"
 }
 
+variable "max_receive_count" {
+  description = "Tentativas de entrega antes de mover a mensagem para a DLQ"
+  type        = number
+  default     = 5
+}
+
 variable "create_rds" {
   type    = bool
   default = true
 }
 
+variable "db_rotation_days" {
+  description = "Intervalo, em dias, da rotação automática da senha do banco"
+  type        = number
+  default     = 30
+}
+
 # 🔹 Essas variáveis permitem CI/CD atualizar imagem sem recriar Lambda
 variable "consumer_image_tag" {
   type    = string
@@ -19,6 +31,11 @@ variable "producer_image_tag" {
   default = "latest"
 }
 
+variable "query_image_tag" {
+  type    = string
+  default = "latest"
+}
+
 variable "region" {
   type    = string
   default = "us-east-1"
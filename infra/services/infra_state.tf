@@ -13,6 +13,7 @@ locals {
   repositories = {
     consumer = data.terraform_remote_state.infra.outputs.ecr_consumer_repo_url
     producer = data.terraform_remote_state.infra.outputs.ecr_producer_repo_url
+    query    = data.terraform_remote_state.infra.outputs.ecr_query_repo_url
   }
 
   queues = {
@@ -19,7 +19,29 @@ resource "aws_lambda_function" "producer" {
 
   environment {
     variables = {
-      SNS_TOPIC_ARN = data.terraform_remote_state.infra.outputs.sns_topic_arn
+      SNS_TOPIC_ARN    = data.terraform_remote_state.infra.outputs.sns_topic_arn
+      OUTBOX_BACKEND   = "dynamodb"
+      OUTBOX_TABLE     = data.terraform_remote_state.infra.outputs.outbox_table_name
+      ALERTS_TOPIC_ARN = data.terraform_remote_state.infra.outputs.alerts_topic_arn
+    }
+  }
+}
+
+# Mesma imagem do producer, republicando o outbox a cada minuto
+resource "aws_lambda_function" "outbox_relay" {
+  function_name    = "${local.name_prefix}-outbox-relay"
+  role             = data.terraform_remote_state.infra.outputs.lambda_role_arn
+  package_type     = "Image"
+  image_uri        = "${data.terraform_remote_state.infra.outputs.ecr_producer_repo_url}:${var.producer_image_tag}"
+  source_code_hash = base64sha256(var.producer_image_tag)
+  timeout          = 60
+
+  environment {
+    variables = {
+      PRODUCER_MODE  = "outbox-relay"
+      SNS_TOPIC_ARN  = data.terraform_remote_state.infra.outputs.sns_topic_arn
+      OUTBOX_BACKEND = "dynamodb"
+      OUTBOX_TABLE   = data.terraform_remote_state.infra.outputs.outbox_table_name
     }
   }
 }
@@ -33,10 +55,15 @@ resource "aws_lambda_function" "consumer_deposit" {
 
   environment {
     variables = {
-      DB_HOST = data.terraform_remote_state.infra.outputs.db_host
-      DB_USER = data.terraform_remote_state.infra.outputs.db_user
-      DB_PASS = data.terraform_remote_state.infra.outputs.db_pass
-      DB_NAME = data.terraform_remote_state.infra.outputs.db_name
+      DB_CREDENTIALS_SOURCE = "secretsmanager"
+      DB_SECRET_ARN         = data.terraform_remote_state.infra.outputs.db_secret_arn
+
+      # SNS_VERIFY_SIGNATURES fica desligado: nas subnets da VPC default, sem
+      # NAT, a Lambda não baixa o certificado de sns.<região>.amazonaws.com e
+      # toda mensagem iria para a DLQ. Até haver saída HTTPS, quem barra
+      # envelopes injetados é a política das filas (aws:SourceArn do tópico).
+
+      ALERTS_TOPIC_ARN = data.terraform_remote_state.infra.outputs.alerts_topic_arn
     }
   }
 
@@ -58,10 +85,41 @@ resource "aws_lambda_function" "consumer_withdraw" {
 
   environment {
     variables = {
-      DB_HOST = data.terraform_remote_state.infra.outputs.db_host
-      DB_USER = data.terraform_remote_state.infra.outputs.db_user
-      DB_PASS = data.terraform_remote_state.infra.outputs.db_pass
-      DB_NAME = data.terraform_remote_state.infra.outputs.db_name
+      DB_CREDENTIALS_SOURCE = "secretsmanager"
+      DB_SECRET_ARN         = data.terraform_remote_state.infra.outputs.db_secret_arn
+
+      # SNS_VERIFY_SIGNATURES fica desligado: nas subnets da VPC default, sem
+      # NAT, a Lambda não baixa o certificado de sns.<região>.amazonaws.com e
+      # toda mensagem iria para a DLQ. Até haver saída HTTPS, quem barra
+      # envelopes injetados é a política das filas (aws:SourceArn do tópico).
+
+      ALERTS_TOPIC_ARN = data.terraform_remote_state.infra.outputs.alerts_topic_arn
+    }
+  }
+
+  dynamic "vpc_config" {
+    for_each = length(data.terraform_remote_state.infra.outputs.private_subnet_ids) > 0 ? [1] : []
+    content {
+      subnet_ids         = data.terraform_remote_state.infra.outputs.private_subnet_ids
+      security_group_ids = [data.terraform_remote_state.infra.outputs.default_sg_id]
+    }
+  }
+}
+
+# Leitura do status das transações (GET /transactions/{id})
+resource "aws_lambda_function" "query" {
+  function_name    = "${local.name_prefix}-query"
+  role             = data.terraform_remote_state.infra.outputs.lambda_role_arn
+  package_type     = "Image"
+  image_uri        = "${local.repositories.query}:${var.query_image_tag}"
+  source_code_hash = base64sha256(var.query_image_tag)
+
+  environment {
+    variables = {
+      DB_CREDENTIALS_SOURCE = "secretsmanager"
+      DB_SECRET_ARN         = data.terraform_remote_state.infra.outputs.db_secret_arn
+
+      OUTBOX_TABLE = data.terraform_remote_state.infra.outputs.outbox_table_name
     }
   }
 
@@ -82,6 +140,9 @@ resource "aws_lambda_event_source_mapping" "deposit_trigger" {
   function_name    = aws_lambda_function.consumer_deposit.arn
   batch_size       = 1
   enabled          = true
+
+  # Devolve à fila apenas os registros que falharam no lote
+  function_response_types = ["ReportBatchItemFailures"]
 }
 
 resource "aws_lambda_event_source_mapping" "withdraw_trigger" {
@@ -89,4 +150,28 @@ resource "aws_lambda_event_source_mapping" "withdraw_trigger" {
   function_name    = aws_lambda_function.consumer_withdraw.arn
   batch_size       = 1
   enabled          = true
+
+  # Devolve à fila apenas os registros que falharam no lote
+  function_response_types = ["ReportBatchItemFailures"]
+}
+
+# =======================
+# ⏰ Agendamento do relay do outbox
+# =======================
+resource "aws_cloudwatch_event_rule" "outbox_relay" {
+  name                = "${local.name_prefix}-outbox-relay"
+  schedule_expression = "rate(1 minute)"
+}
+
+resource "aws_cloudwatch_event_target" "outbox_relay" {
+  rule = aws_cloudwatch_event_rule.outbox_relay.name
+  arn  = aws_lambda_function.outbox_relay.arn
+}
+
+resource "aws_lambda_permission" "outbox_relay_schedule" {
+  statement_id  = "AllowEventBridgeInvoke"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.outbox_relay.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.outbox_relay.arn
 }
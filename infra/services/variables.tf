@@ -25,6 +25,12 @@ variable "consumer_image_tag" {
   type        = string
 }
 
+variable "query_image_tag" {
+  description = "Tag da imagem do Query (consultas de status) no ECR"
+  type        = string
+  default     = "latest"
+}
+
 ##############################################
 # Extras opcionais
 ##############################################
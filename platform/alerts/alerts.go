@@ -0,0 +1,296 @@
+// Package alerts publica alertas operacionais para o on-call. É usado pelo
+// producer e pelo consumer; cada serviço define os próprios gatilhos
+// (kinds) e compartilha daqui o envio, o limitador e a fila assíncrona.
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// =========================================================
+// 🚨 Alertas operacionais
+// =========================================================
+// Alertas vão para o tópico SNS de alertas (ALERTS_TOPIC_ARN), assinado
+// pelo on-call. Sem a variável eles só aparecem no log. O envio é best
+// effort, em segundo plano, e passa por um limitador: alertas iguais
+// (mesmo kind e chave) dentro de DedupWindow são suprimidos, e no máximo
+// MaxPerMinute saem por minuto — o próximo alerta enviado informa
+// quantos foram suprimidos.
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+
+	DedupWindow  = 5 * time.Minute
+	MaxPerMinute = 10
+	SendTimeout  = 2 * time.Second
+
+	// QueueSize é a capacidade da fila de envio; cheia, o alerta é
+	// descartado em vez de segurar quem chamou.
+	QueueSize = 100
+	// FlushTimeout limita a espera pelos alertas pendentes no fim da
+	// invocação.
+	FlushTimeout = time.Second
+)
+
+type Alert struct {
+	Kind     string            `json:"kind"`
+	Severity string            `json:"severity"`
+	Service  string            `json:"service"`
+	Summary  string            `json:"summary"`
+	Details  map[string]string `json:"details,omitempty"`
+	// Suppressed conta os alertas descartados pelo limitador desde o
+	// último envio deste mesmo kind.
+	Suppressed int    `json:"suppressed,omitempty"`
+	Timestamp  string `json:"timestamp"`
+
+	// DedupKey distingue alertas do mesmo kind (ex.: a fila ou a conta).
+	DedupKey string `json:"-"`
+}
+
+// Alerter nunca falha para quem chama: alerta é efeito colateral.
+type Alerter interface {
+	Alert(ctx context.Context, alert Alert)
+}
+
+// SNSClient é o subconjunto do client SNS usado pelos alertas.
+type SNSClient interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// New publica no tópico de ALERTS_TOPIC_ARN ou, sem ele, só registra no
+// log. O limitador e a fila valem para os dois casos.
+func New(cfg aws.Config, service string) *Async {
+	var next Alerter = Log{}
+	if topicARN := os.Getenv("ALERTS_TOPIC_ARN"); topicARN != "" {
+		next = NewSNS(sns.NewFromConfig(cfg), topicARN, service)
+	}
+	return NewAsync(NewThrottled(next, time.Now), QueueSize)
+}
+
+// =========================================================
+// 📝 Somente log
+// =========================================================
+type Log struct{}
+
+func (Log) Alert(ctx context.Context, alert Alert) {
+	slog.WarnContext(ctx, "Alerta operacional", "kind", alert.Kind, "severity", alert.Severity, "summary", alert.Summary)
+}
+
+// =========================================================
+// 📣 SNS
+// =========================================================
+type SNS struct {
+	client   SNSClient
+	topicARN string
+	service  string
+}
+
+func NewSNS(client SNSClient, topicARN, service string) *SNS {
+	return &SNS{client: client, topicARN: topicARN, service: service}
+}
+
+func (a *SNS) Alert(ctx context.Context, alert Alert) {
+	alert.Service = a.service
+	if alert.Timestamp == "" {
+		alert.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		slog.ErrorContext(ctx, "Erro ao serializar alerta", "kind", alert.Kind, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SendTimeout)
+	defer cancel()
+
+	_, err = a.client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(a.topicARN),
+		Subject:  aws.String("[finorbit] " + alert.Kind),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"kind":     {DataType: aws.String("String"), StringValue: aws.String(alert.Kind)},
+			"severity": {DataType: aws.String("String"), StringValue: aws.String(alert.Severity)},
+		},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Erro ao publicar alerta", "kind", alert.Kind, "error", err)
+		return
+	}
+	slog.WarnContext(ctx, "Alerta publicado", "kind", alert.Kind, "severity", alert.Severity, "suppressed", alert.Suppressed)
+}
+
+// =========================================================
+// 🚦 Limitador (dedup + taxa)
+// =========================================================
+// O estado vive no container: basta para um lote ruim não inundar o on-call.
+type Throttled struct {
+	next Alerter
+	now  func() time.Time
+
+	mu          sync.Mutex
+	lastSent    map[string]time.Time
+	suppressed  map[string]int
+	windowStart time.Time
+	sentInWin   int
+}
+
+func NewThrottled(next Alerter, now func() time.Time) *Throttled {
+	return &Throttled{
+		next:       next,
+		now:        now,
+		lastSent:   map[string]time.Time{},
+		suppressed: map[string]int{},
+	}
+}
+
+func (t *Throttled) Alert(ctx context.Context, alert Alert) {
+	if !t.allow(&alert) {
+		return
+	}
+	t.next.Alert(ctx, alert)
+}
+
+// allow decide se o alerta sai agora e, se sair, anexa a contagem de
+// suprimidos do seu kind.
+func (t *Throttled) allow(alert *Alert) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	key := alert.Kind + "|" + alert.DedupKey
+
+	if last, ok := t.lastSent[key]; ok && now.Sub(last) < DedupWindow {
+		t.suppressed[alert.Kind]++
+		return false
+	}
+	if now.Sub(t.windowStart) >= time.Minute {
+		t.windowStart, t.sentInWin = now, 0
+	}
+	if t.sentInWin >= MaxPerMinute {
+		t.suppressed[alert.Kind]++
+		return false
+	}
+
+	t.sentInWin++
+	t.lastSent[key] = now
+	alert.Suppressed = t.suppressed[alert.Kind]
+	delete(t.suppressed, alert.Kind)
+
+	// Descarta chaves vencidas para o mapa não crescer sem limite
+	for k, sent := range t.lastSent {
+		if now.Sub(sent) >= DedupWindow {
+			delete(t.lastSent, k)
+		}
+	}
+	return true
+}
+
+// =========================================================
+// 📬 Envio em segundo plano
+// =========================================================
+// Async enfileira o alerta e devolve na hora; uma goroutine entrega ao
+// próximo Alerter. Assim um SNS lento não consome o prazo da mensagem ou
+// da requisição que disparou o alerta. Flush, no fim da invocação, dá aos
+// pendentes a chance de sair antes de a Lambda congelar o container.
+type Async struct {
+	next  Alerter
+	queue chan queuedAlert
+
+	mu      sync.Mutex
+	pending int
+	idle    chan struct{} // fechado quando pending volta a zero
+}
+
+type queuedAlert struct {
+	ctx   context.Context
+	alert Alert
+}
+
+func NewAsync(next Alerter, size int) *Async {
+	a := &Async{next: next, queue: make(chan queuedAlert, size)}
+	go a.run()
+	return a
+}
+
+func (a *Async) Alert(ctx context.Context, alert Alert) {
+	if alert.Timestamp == "" {
+		alert.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	a.mu.Lock()
+	if a.pending == 0 {
+		a.idle = make(chan struct{})
+	}
+	a.pending++
+	a.mu.Unlock()
+
+	// O envio sobrevive ao fim da requisição, mas mantém trace e
+	// correlation_id do contexto
+	select {
+	case a.queue <- queuedAlert{ctx: context.WithoutCancel(ctx), alert: alert}:
+	default:
+		a.done()
+		slog.WarnContext(ctx, "Fila de alertas cheia, alerta descartado", "kind", alert.Kind)
+	}
+}
+
+func (a *Async) run() {
+	for q := range a.queue {
+		a.next.Alert(q.ctx, q.alert)
+		a.done()
+	}
+}
+
+func (a *Async) done() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending--
+	if a.pending == 0 {
+		close(a.idle)
+	}
+}
+
+// Flush espera os alertas enfileirados até aqui ou o fim do contexto.
+func (a *Async) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	if a.pending == 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	idle, pending := a.idle, a.pending
+	a.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d alerta(s) pendente(s): %w", pending, ctx.Err())
+	}
+}
+
+// Flusher é implementado pelos alerters que enviam em segundo plano.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Flush espera os pendentes de a, se ele enviar em segundo plano, por no
+// máximo FlushTimeout.
+func Flush(ctx context.Context, a Alerter) error {
+	f, ok := a.(Flusher)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, FlushTimeout)
+	defer cancel()
+	return f.Flush(ctx)
+}
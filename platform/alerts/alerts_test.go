@@ -0,0 +1,189 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// recordingAlerter guarda os alertas em vez de publicá-los.
+type recordingAlerter struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recordingAlerter) Alert(_ context.Context, alert Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+}
+
+func (r *recordingAlerter) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.alerts)
+}
+
+type mockSNSClient struct {
+	shouldFail bool
+	published  []*sns.PublishInput
+}
+
+func (m *mockSNSClient) Publish(_ context.Context, input *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	m.published = append(m.published, input)
+	if m.shouldFail {
+		return nil, errors.New("erro simulado SNS")
+	}
+	return &sns.PublishOutput{}, nil
+}
+
+// blockingAlerter segura cada envio até o teste liberar.
+type blockingAlerter struct {
+	release chan struct{}
+	rec     recordingAlerter
+}
+
+func (b *blockingAlerter) Alert(ctx context.Context, alert Alert) {
+	<-b.release
+	b.rec.Alert(ctx, alert)
+}
+
+// fakeClock é um relógio controlado pelo teste.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// =========================================================
+// 🚦 Limitador
+// =========================================================
+func TestThrottled_DeduplicaNaJanela(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 11, 7, 0, 0, 0, 0, time.UTC)}
+	rec := &recordingAlerter{}
+	a := NewThrottled(rec, clock.Now)
+
+	alert := Alert{Kind: "poison_message", DedupKey: "fila"}
+	for i := 0; i < 50; i++ {
+		a.Alert(context.Background(), alert)
+	}
+	if len(rec.alerts) != 1 {
+		t.Fatalf("Lote ruim deveria gerar 1 alerta, obteve %d", len(rec.alerts))
+	}
+
+	clock.Advance(DedupWindow)
+	a.Alert(context.Background(), alert)
+	if len(rec.alerts) != 2 || rec.alerts[1].Suppressed != 49 {
+		t.Errorf("Esperava novo alerta com 49 suprimidos, obteve %+v", rec.alerts)
+	}
+}
+
+func TestThrottled_LimitaPorMinuto(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 11, 7, 0, 0, 0, 0, time.UTC)}
+	rec := &recordingAlerter{}
+	a := NewThrottled(rec, clock.Now)
+
+	for i := 0; i < MaxPerMinute+5; i++ {
+		a.Alert(context.Background(), Alert{Kind: "suspected_duplicate", DedupKey: string(rune('a' + i))})
+	}
+	if len(rec.alerts) != MaxPerMinute {
+		t.Fatalf("Esperava no máximo %d alertas por minuto, obteve %d", MaxPerMinute, len(rec.alerts))
+	}
+
+	clock.Advance(time.Minute)
+	a.Alert(context.Background(), Alert{Kind: "suspected_duplicate", DedupKey: "outra"})
+	if got := rec.alerts[len(rec.alerts)-1].Suppressed; got != 5 {
+		t.Errorf("Esperava 5 suprimidos informados, obteve %d", got)
+	}
+}
+
+// =========================================================
+// 📣 SNS
+// =========================================================
+func TestSNS_PublicaJSONEstruturado(t *testing.T) {
+	mock := &mockSNSClient{}
+	a := NewSNS(mock, "arn:aws:sns:us-east-1:123456789012:alerts", "consumer")
+
+	a.Alert(context.Background(), Alert{Kind: "db_failures", Severity: SeverityCritical, Summary: "teste"})
+
+	if len(mock.published) != 1 {
+		t.Fatalf("Esperava 1 publicação, obteve %d", len(mock.published))
+	}
+	input := mock.published[0]
+	if got := *input.MessageAttributes["kind"].StringValue; got != "db_failures" {
+		t.Errorf("Esperava atributo kind db_failures, obteve %q", got)
+	}
+	if got := *input.MessageAttributes["severity"].StringValue; got != SeverityCritical {
+		t.Errorf("Esperava atributo severity %q, obteve %q", SeverityCritical, got)
+	}
+
+	var body Alert
+	if err := json.Unmarshal([]byte(*input.Message), &body); err != nil {
+		t.Fatalf("Corpo do alerta não é JSON: %v", err)
+	}
+	if body.Service != "consumer" || body.Severity != SeverityCritical || body.Timestamp == "" {
+		t.Errorf("Alerta incompleto: %+v", body)
+	}
+}
+
+func TestSNS_FalhaNaoPropaga(t *testing.T) {
+	a := NewSNS(&mockSNSClient{shouldFail: true}, "arn:alerts", "producer")
+	// Não deve entrar em pânico nem bloquear quem chamou
+	a.Alert(context.Background(), Alert{Kind: "suspected_duplicate"})
+}
+
+// =========================================================
+// 📬 Envio em segundo plano
+// =========================================================
+func TestAsync_NaoBloqueiaQuemChama(t *testing.T) {
+	next := &blockingAlerter{release: make(chan struct{})}
+	a := NewAsync(next, QueueSize)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Alert(ctx, Alert{Kind: "poison_message"})
+	// O fim da requisição não pode cancelar o envio pendente
+	cancel()
+
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if err := a.Flush(short); err == nil {
+		t.Fatal("Flush deveria expirar com o envio ainda bloqueado")
+	}
+
+	close(next.release)
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatalf("Erro inesperado no flush: %v", err)
+	}
+	if next.rec.count() != 1 || next.rec.alerts[0].Timestamp == "" {
+		t.Errorf("Esperava 1 alerta com timestamp da ocorrência, obteve %+v", next.rec.alerts)
+	}
+}
+
+func TestAsync_FilaCheiaDescarta(t *testing.T) {
+	next := &blockingAlerter{release: make(chan struct{})}
+	a := NewAsync(next, 1)
+
+	// Um alerta fica preso no envio, outro na fila; o resto é descartado
+	for i := 0; i < 5; i++ {
+		a.Alert(context.Background(), Alert{Kind: "db_failures"})
+		time.Sleep(time.Millisecond)
+	}
+
+	close(next.release)
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatalf("Erro inesperado no flush: %v", err)
+	}
+	if got := next.rec.count(); got < 1 || got > 2 {
+		t.Errorf("Esperava no máximo 2 alertas entregues, obteve %d", got)
+	}
+}
+
+func TestFlush_IgnoraAlerterSincrono(t *testing.T) {
+	if err := Flush(context.Background(), Log{}); err != nil {
+		t.Errorf("Alerter síncrono não tem o que esperar, obteve %v", err)
+	}
+}
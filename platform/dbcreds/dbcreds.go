@@ -0,0 +1,271 @@
+// Package dbcreds entrega as credenciais do Postgres aos serviços que
+// acessam o banco (consumer e query) e abre conexões que acompanham a
+// rotação da senha.
+package dbcreds
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/lib/pq"
+)
+
+// =========================================================
+// 🔑 Credenciais do banco
+// =========================================================
+// DB_CREDENTIALS_SOURCE escolhe de onde vêm usuário e senha:
+//   - "env" (padrão): DB_HOST, DB_USER, DB_PASS, DB_NAME;
+//   - "secretsmanager": segredo DB_SECRET_ARN, no formato JSON do RDS;
+//   - "file": arquivo DB_CREDENTIALS_FILE com o mesmo JSON.
+//
+// Segredos e arquivos ficam em cache por TTL. Quando o Postgres recusa a
+// senha (rotação), o cache é descartado e a conexão é refeita com a
+// credencial nova.
+const TTL = 15 * time.Minute
+
+type Credentials struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	DBName   string
+}
+
+// Provider entrega as credenciais atuais do banco.
+type Provider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+	// Invalidate descarta o cache após uma falha de autenticação.
+	Invalidate()
+}
+
+// SecretsManagerClient é o subconjunto do client usado para ler o segredo.
+type SecretsManagerClient interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// NewProvider escolhe a origem pelo DB_CREDENTIALS_SOURCE.
+func NewProvider(cfg aws.Config) (Provider, error) {
+	switch source := os.Getenv("DB_CREDENTIALS_SOURCE"); source {
+	case "", "env":
+		return Env{}, nil
+	case "secretsmanager":
+		secretID := os.Getenv("DB_SECRET_ARN")
+		if secretID == "" {
+			return nil, errors.New("DB_SECRET_ARN obrigatória para DB_CREDENTIALS_SOURCE=secretsmanager")
+		}
+		return NewSecretsManager(secretsmanager.NewFromConfig(cfg), secretID, time.Now), nil
+	case "file":
+		path := os.Getenv("DB_CREDENTIALS_FILE")
+		if path == "" {
+			return nil, errors.New("DB_CREDENTIALS_FILE obrigatória para DB_CREDENTIALS_SOURCE=file")
+		}
+		return NewFile(path, time.Now), nil
+	default:
+		return nil, fmt.Errorf("DB_CREDENTIALS_SOURCE desconhecido: %q", source)
+	}
+}
+
+// =========================================================
+// 🌱 Variáveis de ambiente
+// =========================================================
+type Env struct{}
+
+func (Env) Credentials(context.Context) (Credentials, error) {
+	return Credentials{
+		Host:     os.Getenv("DB_HOST"),
+		User:     os.Getenv("DB_USER"),
+		Password: os.Getenv("DB_PASS"),
+		DBName:   os.Getenv("DB_NAME"),
+	}, nil
+}
+
+func (Env) Invalidate() {}
+
+// =========================================================
+// 🗄️ Cache comum a Secrets Manager e arquivo
+// =========================================================
+type Cached struct {
+	fetch func(ctx context.Context) ([]byte, error)
+	now   func() time.Time
+
+	mu        sync.Mutex
+	creds     Credentials
+	fetchedAt time.Time
+	cached    bool
+}
+
+func NewSecretsManager(client SecretsManagerClient, secretID string, now func() time.Time) *Cached {
+	return &Cached{
+		now: now,
+		fetch: func(ctx context.Context) ([]byte, error) {
+			out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(secretID)})
+			if err != nil {
+				return nil, fmt.Errorf("erro ao ler segredo do banco: %w", err)
+			}
+			if out.SecretString == nil {
+				return nil, errors.New("segredo do banco sem SecretString")
+			}
+			return []byte(*out.SecretString), nil
+		},
+	}
+}
+
+func NewFile(path string, now func() time.Time) *Cached {
+	return &Cached{
+		now: now,
+		fetch: func(context.Context) ([]byte, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("erro ao ler credenciais do banco: %w", err)
+			}
+			return data, nil
+		},
+	}
+}
+
+func (c *Cached) Credentials(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached && c.now().Sub(c.fetchedAt) < TTL {
+		return c.creds, nil
+	}
+
+	data, err := c.fetch(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	creds, err := parseCredentials(data)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	c.creds, c.fetchedAt, c.cached = creds, c.now(), true
+	return creds, nil
+}
+
+func (c *Cached) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cached = false
+}
+
+// secretPayload segue o JSON dos segredos do RDS; host e dbname ausentes
+// (segredo gerenciado pelo RDS só traz usuário e senha) vêm do ambiente.
+type secretPayload struct {
+	Host     string      `json:"host"`
+	Port     json.Number `json:"port"`
+	Username string      `json:"username"`
+	Password string      `json:"password"`
+	DBName   string      `json:"dbname"`
+}
+
+func parseCredentials(data []byte) (Credentials, error) {
+	var p secretPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Credentials{}, fmt.Errorf("credenciais do banco em formato inválido: %w", err)
+	}
+	if p.Username == "" || p.Password == "" {
+		return Credentials{}, errors.New("credenciais do banco sem username ou password")
+	}
+
+	creds := Credentials{
+		Host:     p.Host,
+		Port:     p.Port.String(),
+		User:     p.Username,
+		Password: p.Password,
+		DBName:   p.DBName,
+	}
+	if creds.Host == "" {
+		creds.Host = os.Getenv("DB_HOST")
+	}
+	if creds.DBName == "" {
+		creds.DBName = os.Getenv("DB_NAME")
+	}
+	return creds, nil
+}
+
+// =========================================================
+// 🔐 String de conexão
+// =========================================================
+// DSN monta a string key=value do lib/pq; os valores vão entre aspas
+// porque senhas geradas pela rotação podem ter espaços e aspas.
+func (c Credentials) DSN(sslMode string) string {
+	parts := []string{
+		"host=" + dsnValue(c.Host),
+		"user=" + dsnValue(c.User),
+		"password=" + dsnValue(c.Password),
+		"dbname=" + dsnValue(c.DBName),
+		"sslmode=" + dsnValue(sslMode),
+	}
+	if c.Port != "" {
+		parts = append(parts, "port="+dsnValue(c.Port))
+	}
+	return strings.Join(parts, " ")
+}
+
+func dsnValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// =========================================================
+// 🔄 Conector que acompanha a rotação
+// =========================================================
+// Connector busca a credencial a cada conexão nova do pool.
+// Conexões abertas continuam válidas após a rotação; as novas que
+// receberem "senha inválida" descartam o cache e tentam uma vez com o
+// segredo relido.
+type Connector struct {
+	provider Provider
+	sslMode  string
+	dial     func(ctx context.Context, dsn string) (driver.Conn, error)
+}
+
+func NewConnector(provider Provider, sslMode string) *Connector {
+	return &Connector{provider: provider, sslMode: sslMode, dial: dialPostgres}
+}
+
+func dialPostgres(ctx context.Context, dsn string) (driver.Conn, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connect(ctx)
+	if isAuthFailure(err) {
+		slog.WarnContext(ctx, "Postgres recusou a credencial, relendo o segredo", "error", err)
+		c.provider.Invalidate()
+		conn, err = c.connect(ctx)
+	}
+	return conn, err
+}
+
+func (c *Connector) connect(ctx context.Context) (driver.Conn, error) {
+	creds, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.dial(ctx, creds.DSN(c.sslMode))
+}
+
+func (c *Connector) Driver() driver.Driver { return pq.Driver{} }
+
+// isAuthFailure reconhece a classe 28 do Postgres
+// (invalid_authorization_specification / invalid_password).
+func isAuthFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Class() == "28"
+}
@@ -0,0 +1,192 @@
+package dbcreds
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/lib/pq"
+)
+
+// fakeClock é um relógio controlado pelo teste.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// mockSecretsManager devolve o segredo atual e conta as leituras.
+type mockSecretsManager struct {
+	secret string
+	err    error
+	calls  int
+}
+
+func (m *mockSecretsManager) GetSecretValue(_ context.Context, input *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &secretsmanager.GetSecretValueOutput{ARN: input.SecretId, SecretString: aws.String(m.secret)}, nil
+}
+
+// =========================================================
+// 🔑 Provedores
+// =========================================================
+func TestEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "db.local")
+	t.Setenv("DB_USER", "finorbit")
+	t.Setenv("DB_PASS", "segredo")
+	t.Setenv("DB_NAME", "finorbit")
+
+	creds, err := Env{}.Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if creds.Host != "db.local" || creds.User != "finorbit" || creds.Password != "segredo" || creds.DBName != "finorbit" {
+		t.Errorf("Credenciais incorretas: %+v", creds)
+	}
+}
+
+func TestSecretsManager_CacheEInvalidacao(t *testing.T) {
+	t.Setenv("DB_HOST", "db.local")
+	t.Setenv("DB_NAME", "finorbit")
+	clock := &fakeClock{t: time.Date(2025, 11, 7, 0, 0, 0, 0, time.UTC)}
+	client := &mockSecretsManager{secret: `{"username":"finorbit_admin","password":"antiga"}`}
+	provider := NewSecretsManager(client, "arn:secret", clock.Now)
+
+	for i := 0; i < 3; i++ {
+		if _, err := provider.Credentials(context.Background()); err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("Segredo deveria vir do cache, houve %d leituras", client.calls)
+	}
+
+	// Rotação: o cache só é relido após Invalidate ou ao expirar
+	client.secret = `{"username":"finorbit_admin","password":"nova"}`
+	provider.Invalidate()
+	creds, _ := provider.Credentials(context.Background())
+	if creds.Password != "nova" || client.calls != 2 {
+		t.Errorf("Esperava senha nova após Invalidate, obteve %q (%d leituras)", creds.Password, client.calls)
+	}
+	if creds.Host != "db.local" || creds.DBName != "finorbit" {
+		t.Errorf("Host e dbname deveriam vir do ambiente: %+v", creds)
+	}
+
+	clock.Advance(TTL)
+	provider.Credentials(context.Background())
+	if client.calls != 3 {
+		t.Errorf("Esperava releitura após o TTL, houve %d leituras", client.calls)
+	}
+}
+
+func TestSecretsManager_Erros(t *testing.T) {
+	cases := map[string]*mockSecretsManager{
+		"falha na API":  {err: errors.New("AccessDenied")},
+		"JSON inválido": {secret: "não é json"},
+		"sem password":  {secret: `{"username":"u"}`},
+	}
+	for name, client := range cases {
+		provider := NewSecretsManager(client, "arn:secret", time.Now)
+		if _, err := provider.Credentials(context.Background()); err == nil {
+			t.Errorf("%s: esperava erro", name)
+		}
+	}
+}
+
+func TestFile_FormatoRDS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	os.WriteFile(path, []byte(`{"host":"rds.local","port":5432,"username":"u","password":"p","dbname":"finorbit"}`), 0o600)
+
+	creds, err := NewFile(path, time.Now).Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if creds.Host != "rds.local" || creds.Port != "5432" || creds.User != "u" || creds.DBName != "finorbit" {
+		t.Errorf("Credenciais incorretas: %+v", creds)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Setenv("DB_CREDENTIALS_SOURCE", "")
+	if p, err := NewProvider(aws.Config{}); err != nil || p != (Env{}) {
+		t.Errorf("Esperava Env por padrão, obteve %T / %v", p, err)
+	}
+
+	t.Setenv("DB_CREDENTIALS_SOURCE", "secretsmanager")
+	t.Setenv("DB_SECRET_ARN", "")
+	if _, err := NewProvider(aws.Config{}); err == nil {
+		t.Error("Esperava erro sem DB_SECRET_ARN")
+	}
+
+	t.Setenv("DB_CREDENTIALS_SOURCE", "vault")
+	if _, err := NewProvider(aws.Config{}); err == nil {
+		t.Error("Esperava erro para origem desconhecida")
+	}
+}
+
+func TestCredentials_DSNEscapaValores(t *testing.T) {
+	dsn := Credentials{Host: "h", User: "u", Password: `p a's\s`, DBName: "d"}.DSN("require")
+
+	if !strings.Contains(dsn, `password='p a\'s\\s'`) {
+		t.Errorf("Senha deveria ir escapada, obteve %s", dsn)
+	}
+	if strings.Contains(dsn, "port=") {
+		t.Errorf("Porta vazia não deveria entrar na string, obteve %s", dsn)
+	}
+	if _, err := pq.NewConnector(dsn); err != nil {
+		t.Errorf("lib/pq recusou a string de conexão: %v", err)
+	}
+}
+
+// =========================================================
+// 🔄 Reconexão após rotação
+// =========================================================
+func TestConnector_FalhaDeAutenticacaoReleSegredo(t *testing.T) {
+	client := &mockSecretsManager{secret: `{"username":"u","password":"antiga"}`}
+	provider := NewSecretsManager(client, "arn:secret", time.Now)
+	provider.Credentials(context.Background()) // cache com a senha antiga
+
+	// A rotação troca a senha no segredo e no banco
+	client.secret = `{"username":"u","password":"nova"}`
+
+	var dsns []string
+	connector := NewConnector(provider, "disable")
+	connector.dial = func(_ context.Context, dsn string) (driver.Conn, error) {
+		dsns = append(dsns, dsn)
+		if strings.Contains(dsn, "antiga") {
+			return nil, &pq.Error{Code: "28P01", Message: "password authentication failed"}
+		}
+		return nil, nil
+	}
+
+	if _, err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Esperava reconectar com a senha nova, obteve %v", err)
+	}
+	if len(dsns) != 2 || !strings.Contains(dsns[1], "nova") {
+		t.Errorf("Esperava nova tentativa com a senha renovada, obteve %v", dsns)
+	}
+}
+
+func TestConnector_OutrosErrosNaoReleemSegredo(t *testing.T) {
+	client := &mockSecretsManager{secret: `{"username":"u","password":"p"}`}
+	connector := NewConnector(NewSecretsManager(client, "arn:secret", time.Now), "disable")
+	connector.dial = func(context.Context, string) (driver.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	if _, err := connector.Connect(context.Background()); err == nil {
+		t.Fatal("Esperava o erro de conexão")
+	}
+	if client.calls != 1 {
+		t.Errorf("Erro fora da classe 28 não deveria reler o segredo, houve %d leituras", client.calls)
+	}
+}
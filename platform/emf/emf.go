@@ -0,0 +1,232 @@
+// Package emf registra as métricas de negócio dos serviços e as publica no
+// stdout em Embedded Metric Format, que o CloudWatch Logs converte em
+// métricas sem chamadas à API.
+package emf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =========================================================
+// 📈 Métricas de negócio (CloudWatch EMF)
+// =========================================================
+//
+// Os handlers registram métricas pela interface Metrics. Em produção elas
+// são acumuladas durante a invocação e escritas ao fim dela.
+// METRICS_BACKEND: "emf" (padrão) ou "none"; METRICS_NAMESPACE (padrão FinOrbit).
+const (
+	UnitCount        = "Count"
+	UnitMilliseconds = "Milliseconds"
+	UnitNone         = "None"
+
+	DefaultNamespace = "FinOrbit"
+)
+
+type Metrics interface {
+	// Record acumula um valor; dims são somadas à dimensão Service.
+	Record(name string, value float64, unit string, dims map[string]string)
+	// Flush publica o que foi acumulado — chamado ao fim de cada invocação.
+	Flush(ctx context.Context) error
+}
+
+// New escolhe o backend por METRICS_BACKEND; service vira a dimensão
+// Service de todas as métricas.
+func New(service string) (Metrics, error) {
+	switch backend := os.Getenv("METRICS_BACKEND"); backend {
+	case "", "emf":
+		namespace := os.Getenv("METRICS_NAMESPACE")
+		if namespace == "" {
+			namespace = DefaultNamespace
+		}
+		return NewWriter(os.Stdout, namespace, service), nil
+	case "none":
+		return Noop{}, nil
+	default:
+		return nil, fmt.Errorf("METRICS_BACKEND desconhecido: %q", backend)
+	}
+}
+
+// TypeDims é a dimensão usada nas métricas por tipo de transação.
+func TypeDims(txType string) map[string]string {
+	return map[string]string{"Type": txType}
+}
+
+// =========================================================
+// 🚫 No-op
+// =========================================================
+type Noop struct{}
+
+func (Noop) Record(string, float64, string, map[string]string) {}
+func (Noop) Flush(context.Context) error                       { return nil }
+
+// =========================================================
+// 📤 EMF
+// =========================================================
+type series struct {
+	dims   map[string]string
+	values map[string][]float64
+	units  map[string]string
+}
+
+type Writer struct {
+	mu        sync.Mutex
+	w         io.Writer
+	namespace string
+	service   string
+	series    map[string]*series
+}
+
+func NewWriter(w io.Writer, namespace, service string) *Writer {
+	return &Writer{w: w, namespace: namespace, service: service, series: map[string]*series{}}
+}
+
+func (m *Writer) Record(name string, value float64, unit string, dims map[string]string) {
+	all := map[string]string{"Service": m.service}
+	for k, v := range dims {
+		all[k] = v
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := dimsKey(all)
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{dims: all, values: map[string][]float64{}, units: map[string]string{}}
+		m.series[key] = s
+	}
+	s.values[name] = append(s.values[name], value)
+	s.units[name] = unit
+}
+
+// Flush escreve uma linha EMF por conjunto de dimensões.
+func (m *Writer) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	pending := m.series
+	m.series = map[string]*series{}
+	m.mu.Unlock()
+
+	keys := make([]string, 0, len(pending))
+	for k := range pending {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		line, err := emfLine(m.namespace, pending[k], time.Now())
+		if err != nil {
+			return err
+		}
+		if _, err := m.w.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("erro ao escrever métricas: %w", err)
+		}
+	}
+	return nil
+}
+
+func emfLine(namespace string, s *series, now time.Time) ([]byte, error) {
+	type metricDef struct {
+		Name string `json:"Name"`
+		Unit string `json:"Unit"`
+	}
+
+	dimNames := make([]string, 0, len(s.dims))
+	for k := range s.dims {
+		dimNames = append(dimNames, k)
+	}
+	sort.Strings(dimNames)
+
+	names := make([]string, 0, len(s.values))
+	for k := range s.values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	doc := map[string]any{}
+	defs := make([]metricDef, 0, len(names))
+	for _, name := range names {
+		defs = append(defs, metricDef{Name: name, Unit: s.units[name]})
+		if values := s.values[name]; len(values) == 1 {
+			doc[name] = values[0]
+		} else {
+			doc[name] = values
+		}
+	}
+	for k, v := range s.dims {
+		doc[k] = v
+	}
+	doc["_aws"] = map[string]any{
+		"Timestamp": now.UnixMilli(),
+		"CloudWatchMetrics": []any{map[string]any{
+			"Namespace":  namespace,
+			"Dimensions": [][]string{dimNames},
+			"Metrics":    defs,
+		}},
+	}
+	return json.Marshal(doc)
+}
+
+func dimsKey(dims map[string]string) string {
+	parts := make([]string, 0, len(dims))
+	for k, v := range dims {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// =========================================================
+// 🧪 Memória (testes)
+// =========================================================
+type Recorded struct {
+	Name  string
+	Value float64
+	Unit  string
+	Dims  map[string]string
+}
+
+type Memory struct {
+	mu      sync.Mutex
+	records []Recorded
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Record(name string, value float64, unit string, dims map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, Recorded{Name: name, Value: value, Unit: unit, Dims: dims})
+}
+
+func (m *Memory) Flush(context.Context) error { return nil }
+
+// Records devolve uma cópia do que foi registrado.
+func (m *Memory) Records() []Recorded {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Recorded(nil), m.records...)
+}
+
+// Sum soma os valores registrados com o nome dado.
+func (m *Memory) Sum(name string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var total float64
+	for _, r := range m.records {
+		if r.Name == name {
+			total += r.Value
+		}
+	}
+	return total
+}
@@ -0,0 +1,91 @@
+package emf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// =========================================================
+// 📤 Formato EMF
+// =========================================================
+func TestWriter_LinhaPorConjuntoDeDimensoes(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewWriter(&buf, "FinOrbitTeste", "producer")
+
+	m.Record("TransactionCount", 1, UnitCount, TypeDims("deposit"))
+	m.Record("TransactionCount", 1, UnitCount, TypeDims("deposit"))
+	m.Record("TransactionAmount", 10.5, UnitNone, TypeDims("deposit"))
+	m.Record("RequestsAccepted", 1, UnitCount, nil)
+	if err := m.Flush(context.Background()); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Esperava 2 linhas EMF, obteve %d: %s", len(lines), buf.String())
+	}
+
+	var doc struct {
+		AWS struct {
+			CloudWatchMetrics []struct {
+				Namespace  string
+				Dimensions [][]string
+				Metrics    []struct{ Name, Unit string }
+			}
+		} `json:"_aws"`
+		Service           string
+		Type              string
+		TransactionCount  []float64
+		TransactionAmount float64
+	}
+	// Linhas ordenadas pela chave das dimensões: Service=producer,Type=deposit vem depois
+	if err := json.Unmarshal([]byte(lines[1]), &doc); err != nil {
+		t.Fatalf("Linha EMF inválida: %v", err)
+	}
+	cw := doc.AWS.CloudWatchMetrics[0]
+	if cw.Namespace != "FinOrbitTeste" || len(cw.Dimensions) != 1 || strings.Join(cw.Dimensions[0], ",") != "Service,Type" {
+		t.Errorf("Cabeçalho EMF incorreto: %+v", cw)
+	}
+	if doc.Service != "producer" || doc.Type != "deposit" {
+		t.Errorf("Dimensões ausentes: %s", lines[1])
+	}
+	if len(doc.TransactionCount) != 2 || doc.TransactionAmount != 10.5 {
+		t.Errorf("Valores incorretos: %s", lines[1])
+	}
+
+	// Flush esvazia o acumulado
+	buf.Reset()
+	_ = m.Flush(context.Background())
+	if buf.Len() != 0 {
+		t.Errorf("Segundo flush não deveria escrever nada, obteve %s", buf.String())
+	}
+}
+
+// =========================================================
+// ⚙️ Backend
+// =========================================================
+func TestNew_Backend(t *testing.T) {
+	t.Setenv("METRICS_BACKEND", "none")
+	if m, err := New("consumer"); err != nil || m != (Noop{}) {
+		t.Errorf("Esperava Noop com METRICS_BACKEND=none, obteve %T, %v", m, err)
+	}
+
+	t.Setenv("METRICS_BACKEND", "statsd")
+	if _, err := New("consumer"); err == nil {
+		t.Error("Esperava erro para backend desconhecido")
+	}
+}
+
+func TestMemory_Sum(t *testing.T) {
+	m := NewMemory()
+	m.Record("MessagesPersisted", 1, UnitCount, nil)
+	m.Record("MessagesPersisted", 2, UnitCount, nil)
+	m.Record("MessagesDropped", 1, UnitCount, nil)
+
+	if m.Sum("MessagesPersisted") != 3 || len(m.Records()) != 3 {
+		t.Errorf("Soma incorreta: %+v", m.Records())
+	}
+}
@@ -0,0 +1,156 @@
+// Package logging configura o slog comum aos serviços: JSON no stdout,
+// contas mascaradas, credenciais e corpos removidos, e o correlation_id e
+// o trace do contexto em cada linha.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// =========================================================
+// 📝 Logs estruturados (slog)
+// =========================================================
+//
+// Um objeto JSON por linha no stdout, pronto para o CloudWatch Logs
+// Insights. LOG_LEVEL escolhe o nível mínimo: debug, info (padrão), warn
+// ou error.
+const (
+	KeyCorrelationID = "correlation_id"
+	KeyError         = "error"
+)
+
+// maskedKeys identificam contas: aparecem só parcialmente.
+var maskedKeys = map[string]bool{
+	"user_id":    true,
+	"account_id": true,
+}
+
+// redactedKeys nunca vão para o log — credenciais e corpos crus.
+var redactedKeys = map[string]bool{
+	"password": true,
+	"secret":   true,
+	"token":    true,
+	"body":     true,
+	"payload":  true,
+}
+
+// Setup instala o logger JSON como padrão do slog, no nível de LOG_LEVEL.
+func Setup() error {
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(New(os.Stdout, level))
+	return nil
+}
+
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("LOG_LEVEL desconhecido: %q", name)
+	}
+}
+
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	})})
+}
+
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	switch {
+	case redactedKeys[key]:
+		return slog.String(a.Key, "[REDACTED]")
+	case maskedKeys[key]:
+		return slog.String(a.Key, MaskAccountID(a.Value.String()))
+	}
+	return a
+}
+
+// MaskAccountID mantém só o início e o fim do identificador, o bastante
+// para correlacionar linhas sem expor a conta.
+func MaskAccountID(id string) string {
+	if len(id) <= 8 {
+		return "****"
+	}
+	return id[:4] + "****" + id[len(id)-4:]
+}
+
+// Fatal registra o erro e encerra o processo.
+func Fatal(msg string, err error) {
+	slog.Error(msg, KeyError, err)
+	os.Exit(1)
+}
+
+// =========================================================
+// 🔗 Correlation ID no contexto
+// =========================================================
+// MaxCorrelationIDLength limita valores vindos de fora (header ou atributo
+// da mensagem), que vão parar em logs e atributos.
+const MaxCorrelationIDLength = 128
+
+// ValidCorrelationID aceita até MaxCorrelationIDLength caracteres do ASCII
+// visível.
+func ValidCorrelationID(id string) bool {
+	if id == "" || len(id) > MaxCorrelationIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+type correlationKey struct{}
+
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// contextHandler acrescenta o correlation_id e o trace do contexto a cada
+// registro, para que toda chamada *Context saia com eles.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := CorrelationIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String(KeyCorrelationID, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func logLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var line map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &line); err != nil {
+		t.Fatalf("Linha de log não é JSON: %q", buf.String())
+	}
+	return line
+}
+
+// =========================================================
+// 📝 Redação
+// =========================================================
+func TestNew_MascaraContaERemoveSensiveis(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, slog.LevelInfo).Info("teste",
+		"user_id", "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b",
+		"account_id", "curta",
+		"body", `{"amount":"10"}`,
+		"Password", "segredo",
+	)
+
+	line := logLine(t, &buf)
+	if line["user_id"] != "6f1c****3a4b" {
+		t.Errorf("user_id deveria ser mascarado, obteve %v", line["user_id"])
+	}
+	if line["account_id"] != "****" {
+		t.Errorf("account_id curto deveria ser totalmente mascarado, obteve %v", line["account_id"])
+	}
+	if line["body"] != "[REDACTED]" || line["Password"] != "[REDACTED]" {
+		t.Errorf("Campos sensíveis vazaram: %v", line)
+	}
+}
+
+func TestNew_NivelConfiguravel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelWarn)
+
+	logger.Info("informativo")
+	logger.Warn("aviso")
+	if line := logLine(t, &buf); line["msg"] != "aviso" {
+		t.Errorf("Com nível warn, esperava só o aviso, obteve %v", line)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]slog.Level{"": slog.LevelInfo, "DEBUG": slog.LevelDebug, "warn": slog.LevelWarn, "error": slog.LevelError}
+	for name, want := range cases {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; esperava %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Esperava erro para nível desconhecido")
+	}
+}
+
+// =========================================================
+// 🔗 Correlation ID
+// =========================================================
+func TestNew_CorrelationIDDoContexto(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, slog.LevelInfo).InfoContext(WithCorrelationID(context.Background(), "pedido-7"), "teste")
+
+	if got := logLine(t, &buf)[KeyCorrelationID]; got != "pedido-7" {
+		t.Errorf("Esperava correlation_id no log, obteve %v", got)
+	}
+}
+
+func TestValidCorrelationID(t *testing.T) {
+	cases := map[string]bool{
+		"pedido-7":                     true,
+		"":                             false,
+		"com espaço":                   false,
+		"linha\nnova":                  false,
+		"ação":                         false,
+		strings.Repeat("a", 128):       true,
+		strings.Repeat("a", 128) + "b": false,
+	}
+	for id, want := range cases {
+		if got := ValidCorrelationID(id); got != want {
+			t.Errorf("ValidCorrelationID(%q) = %v, esperava %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package tracing monta o OpenTelemetry dos serviços a partir das variáveis
+// OTEL_*: exportador, resource e propagação W3C (traceparent).
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"finorbit/platform/logging"
+)
+
+// =========================================================
+// 🔭 Tracing (OpenTelemetry)
+// =========================================================
+//
+// OTEL_TRACES_EXPORTER escolhe o exportador: "otlp" (OTLP/HTTP, configurado
+// pelas variáveis OTEL_EXPORTER_OTLP_*), "stdout" (execução local) ou
+// vazio/"none" para desligar.
+
+// Disabled devolve o provider e o propagador do tracing desligado: spans
+// no-op e nada propagado.
+func Disabled() (trace.TracerProvider, propagation.TextMapPropagator) {
+	return noop.NewTracerProvider(), propagation.NewCompositeTextMapPropagator()
+}
+
+// Setup cria o provider conforme OTEL_TRACES_EXPORTER e o registra como
+// global do OpenTelemetry. serviceName é o service.name padrão do resource.
+func Setup(ctx context.Context, serviceName string) (trace.TracerProvider, propagation.TextMapPropagator, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch name := os.Getenv("OTEL_TRACES_EXPORTER"); name {
+	case "", "none":
+		provider, propagator := Disabled()
+		return provider, propagator, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, nil, fmt.Errorf("OTEL_TRACES_EXPORTER desconhecido: %q", name)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao criar exportador de traces: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME e OTEL_RESOURCE_ATTRIBUTES sobrescrevem o padrão
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao montar resource do tracing: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	propagator := propagation.TraceContext{}
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return provider, propagator, nil
+}
+
+// Flush envia os spans pendentes antes de a Lambda congelar o container
+// entre invocações.
+func Flush(ctx context.Context, provider trace.TracerProvider) {
+	sdk, ok := provider.(*sdktrace.TracerProvider)
+	if !ok {
+		return
+	}
+	if err := sdk.ForceFlush(ctx); err != nil {
+		slog.WarnContext(ctx, "Erro ao exportar spans", logging.KeyError, err)
+	}
+}
+
+func Shutdown(ctx context.Context, provider trace.TracerProvider) {
+	sdk, ok := provider.(*sdktrace.TracerProvider)
+	if !ok {
+		return
+	}
+	if err := sdk.Shutdown(ctx); err != nil {
+		slog.WarnContext(ctx, "Erro ao encerrar tracing", logging.KeyError, err)
+	}
+}
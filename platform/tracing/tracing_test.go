@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// =========================================================
+// 🔭 Exportador
+// =========================================================
+func TestSetup_DesligadoPorPadrao(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+
+	provider, propagator, err := Setup(context.Background(), "finorbit-teste")
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if _, ok := provider.(*sdktrace.TracerProvider); ok {
+		t.Error("Sem exportador, o provider deveria ser no-op")
+	}
+	if len(propagator.Fields()) != 0 {
+		t.Errorf("Sem tracing, nada deveria ser propagado, obteve %v", propagator.Fields())
+	}
+	// No-op não tem o que enviar nem encerrar
+	Flush(context.Background(), provider)
+	Shutdown(context.Background(), provider)
+}
+
+func TestSetup_Stdout(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "stdout")
+
+	provider, propagator, err := Setup(context.Background(), "finorbit-teste")
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	defer Shutdown(context.Background(), provider)
+
+	if _, ok := provider.(*sdktrace.TracerProvider); !ok {
+		t.Errorf("Esperava provider do SDK, obteve %T", provider)
+	}
+	if fields := propagator.Fields(); len(fields) == 0 || fields[0] != "traceparent" {
+		t.Errorf("Esperava propagação W3C, obteve %v", fields)
+	}
+}
+
+func TestSetup_ExportadorDesconhecido(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+
+	if _, _, err := Setup(context.Background(), "finorbit-teste"); err == nil {
+		t.Error("Esperava erro para exportador desconhecido")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	txevents "finorbit/events"
+	"finorbit/platform/alerts"
+	"finorbit/platform/logging"
+)
+
+// ===============================
+// Alertas operacionais
+// ===============================
+//
+// Envio, limitador e fila ficam em finorbit/platform/alerts; aqui só os
+// gatilhos do producer. Os alertas saem em segundo plano e o handler
+// espera os pendentes antes de responder (flushAlerts).
+const (
+	alertSuspectedDuplicate = "suspected_duplicate"
+	alertDeadOutboxEntry    = "dead_outbox_entry"
+)
+
+var alerter alerts.Alerter = alerts.Log{}
+
+// flushAlerts espera os alertas da invocação; o que não sair a tempo
+// segue na fila para a próxima.
+func flushAlerts(ctx context.Context) {
+	if err := alerts.Flush(ctx, alerter); err != nil {
+		slog.WarnContext(ctx, "Alertas ainda pendentes no fim da invocação", logKeyError, err)
+	}
+}
+
+// ===============================
+// Suspeita de duplicidade
+// ===============================
+// Sem Idempotency-Key, um clique duplo vira duas transações. O detector
+// lembra os envios recentes (conta, tipo e valor) por duplicateWindow e
+// sinaliza quando o mesmo envio se repete — a transação segue aceita.
+const duplicateWindow = 60 * time.Second
+
+type duplicateDetector struct {
+	now func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+var recentSubmissions = newDuplicateDetector(time.Now)
+
+func newDuplicateDetector(now func() time.Time) *duplicateDetector {
+	return &duplicateDetector{now: now, seen: map[string]time.Time{}}
+}
+
+func submissionKey(event txevents.TransactionEvent) string {
+	return event.UserID + "|" + event.Type + "|" + event.Amount.String()
+}
+
+// Seen registra o envio e informa se ele já tinha aparecido na janela.
+func (d *duplicateDetector) Seen(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	for k, at := range d.seen {
+		if now.Sub(at) >= duplicateWindow {
+			delete(d.seen, k)
+		}
+	}
+
+	_, dup := d.seen[key]
+	d.seen[key] = now
+	return dup
+}
+
+// alertSuspectedDuplicateSubmission avisa o on-call sobre o envio repetido,
+// com a conta mascarada como nos logs.
+func alertSuspectedDuplicateSubmission(ctx context.Context, event txevents.TransactionEvent) {
+	alerter.Alert(ctx, alerts.Alert{
+		Kind:     alertSuspectedDuplicate,
+		Severity: alerts.SeverityWarning,
+		Summary:  "Transação repetida sem Idempotency-Key",
+		Details: map[string]string{
+			logKeyEventID:       event.EventID,
+			logKeyUserID:        logging.MaskAccountID(event.UserID),
+			logKeyType:          event.Type,
+			logKeyAmount:        event.Amount.String(),
+			logKeyCorrelationID: logging.CorrelationIDFromContext(ctx),
+		},
+		DedupKey: event.UserID,
+	})
+}
+
+// ===============================
+// Outbox esgotado
+// ===============================
+
+// alertDeadOutbox avisa quando uma entrada esgota maxOutboxAttempts: o
+// cliente recebeu aceite, mas o evento não chega ao broker sem
+// intervenção manual.
+func alertDeadOutbox(ctx context.Context, entry OutboxEntry, cause error) {
+	alerter.Alert(ctx, alerts.Alert{
+		Kind:     alertDeadOutboxEntry,
+		Severity: alerts.SeverityCritical,
+		Summary:  "Evento do outbox esgotou as tentativas de publicação",
+		Details: map[string]string{
+			logKeyEventID:       entry.EventID,
+			"attempts":          fmt.Sprint(entry.Attempts + 1),
+			logKeyError:         cause.Error(),
+			logKeyCorrelationID: entry.CorrelationID,
+		},
+		DedupKey: entry.EventID,
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"finorbit/platform/alerts"
+)
+
+// recordingAlerter guarda os alertas em vez de publicá-los.
+type recordingAlerter struct {
+	alerts []alerts.Alert
+}
+
+func (r *recordingAlerter) Alert(_ context.Context, alert alerts.Alert) {
+	r.alerts = append(r.alerts, alert)
+}
+
+// useRecordingAlerter troca o alerter global durante o teste.
+func useRecordingAlerter(t *testing.T) *recordingAlerter {
+	t.Helper()
+	rec := &recordingAlerter{}
+	saved := alerter
+	alerter = rec
+	t.Cleanup(func() { alerter = saved })
+	return rec
+}
+
+// fakeClock é um relógio controlado pelo teste.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// ------------------------
+// 1️⃣ Suspeita de duplicidade
+// ------------------------
+func TestHandler_EnvioRepetidoSemIdempotencyKeyAlerta(t *testing.T) {
+	rec := useRecordingAlerter(t)
+	useSNSMock(t, &mockSNSClient{})
+	saved := recentSubmissions
+	recentSubmissions = newDuplicateDetector(time.Now)
+	t.Cleanup(func() { recentSubmissions = saved })
+
+	for i := 0; i < 2; i++ {
+		if resp, _ := handler(context.Background(), postTransaction(nil)); resp.StatusCode != 200 {
+			t.Fatalf("Tentativa %d: esperava 200, obteve %d", i+1, resp.StatusCode)
+		}
+	}
+
+	if len(rec.alerts) != 1 || rec.alerts[0].Kind != alertSuspectedDuplicate {
+		t.Fatalf("Esperava 1 alerta de duplicidade, obteve %+v", rec.alerts)
+	}
+	if user := rec.alerts[0].Details[logKeyUserID]; strings.Contains(testAccountID, user) {
+		t.Errorf("Conta deveria ir mascarada no alerta, obteve %q", user)
+	}
+}
+
+func TestHandler_ComIdempotencyKeyNaoAlerta(t *testing.T) {
+	rec := useRecordingAlerter(t)
+	useSNSMock(t, &mockSNSClient{})
+	saved := recentSubmissions
+	recentSubmissions = newDuplicateDetector(time.Now)
+	t.Cleanup(func() { recentSubmissions = saved })
+
+	headers := map[string]string{"Idempotency-Key": "pedido-7"}
+	for i := 0; i < 2; i++ {
+		handler(context.Background(), postTransaction(headers))
+	}
+	if len(rec.alerts) != 0 {
+		t.Errorf("Retentativa com Idempotency-Key não é suspeita, obteve %+v", rec.alerts)
+	}
+}
+
+func TestDuplicateDetector_EsqueceAposJanela(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 11, 7, 0, 0, 0, 0, time.UTC)}
+	d := newDuplicateDetector(clock.Now)
+
+	if d.Seen("k") {
+		t.Fatal("Primeiro envio não é duplicado")
+	}
+	if !d.Seen("k") {
+		t.Fatal("Segundo envio na janela deveria ser duplicado")
+	}
+	clock.Advance(duplicateWindow)
+	if d.Seen("k") {
+		t.Error("Envio após a janela não deveria ser duplicado")
+	}
+}
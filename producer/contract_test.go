@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	txevents "finorbit/events"
+)
+
+// ------------------------
+// 📜 Contrato — o evento publicado pelo handler tem exatamente os campos
+// do JSON de referência do módulo finorbit/events
+// ------------------------
+const contractFixture = "../events/testdata/transaction_event_v1.json"
+
+func jsonFields(t *testing.T, data []byte) map[string]bool {
+	t.Helper()
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("JSON inválido: %v", err)
+	}
+	fields := make(map[string]bool, len(raw))
+	for name := range raw {
+		fields[name] = true
+	}
+	return fields
+}
+
+func TestContrato_ProducerPublicaEventoV1(t *testing.T) {
+	golden, err := os.ReadFile(contractFixture)
+	if err != nil {
+		t.Fatalf("Erro ao ler contrato: %v", err)
+	}
+
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	resp, _ := handler(context.Background(), postTransaction(nil))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+	message := []byte(*mock.published[0].Message)
+
+	want, got := jsonFields(t, golden), jsonFields(t, message)
+	for name := range want {
+		if !got[name] {
+			t.Errorf("Campo %q do contrato ausente no evento publicado", name)
+		}
+	}
+	for name := range got {
+		if !want[name] {
+			t.Errorf("Campo %q publicado não existe no contrato", name)
+		}
+	}
+
+	event, err := txevents.Decode(message)
+	if err != nil {
+		t.Fatalf("Evento publicado não decodifica: %v", err)
+	}
+	if event.SchemaVersion != txevents.SchemaVersion {
+		t.Errorf("Esperava schema_version %d, obteve %d", txevents.SchemaVersion, event.SchemaVersion)
+	}
+	if err := event.Validate(); err != nil {
+		t.Errorf("Evento publicado fora do contrato: %v", err)
+	}
+}
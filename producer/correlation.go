@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pborman/uuid"
+
+	"finorbit/platform/logging"
+)
+
+// ===============================
+// Correlation ID
+// ===============================
+//
+// Cada requisição ganha um correlation_id — o X-Correlation-ID enviado pelo
+// cliente ou, na falta dele, o RequestID do API Gateway. Ele volta na
+// resposta, vai para o outbox e segue como atributo da mensagem até o
+// consumer, que o grava junto da transação.
+const (
+	correlationHeader    = "X-Correlation-ID"
+	correlationAttribute = "correlation_id"
+)
+
+// correlationID escolhe o identificador da requisição. Valores do cliente
+// longos demais ou com caracteres fora do ASCII visível são ignorados, já
+// que vão parar em logs e atributos de mensagem.
+func correlationID(req events.APIGatewayV2HTTPRequest) string {
+	if id := strings.TrimSpace(headerValue(req.Headers, correlationHeader)); logging.ValidCorrelationID(id) {
+		return id
+	}
+	if req.RequestContext.RequestID != "" {
+		return req.RequestContext.RequestID
+	}
+	return uuid.NewRandom().String()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+
+	txevents "finorbit/events"
+	"finorbit/platform/logging"
+)
+
+func correlationAttributeOf(input *sns.PublishInput) string {
+	attr, ok := input.MessageAttributes[correlationAttribute]
+	if !ok || attr.StringValue == nil {
+		return ""
+	}
+	return *attr.StringValue
+}
+
+// ------------------------
+// 1️⃣ Origem do correlation_id
+// ------------------------
+func TestCorrelationID_Origem(t *testing.T) {
+	withRequestID := func(headers map[string]string) events.APIGatewayV2HTTPRequest {
+		req := postTransaction(headers)
+		req.RequestContext.RequestID = "req-gw-1"
+		return req
+	}
+
+	cases := map[string]struct {
+		req  events.APIGatewayV2HTTPRequest
+		want string
+	}{
+		"header do cliente":    {withRequestID(map[string]string{"x-correlation-id": " pedido-7 "}), "pedido-7"},
+		"RequestID do gateway": {withRequestID(nil), "req-gw-1"},
+		"header com espaço":    {withRequestID(map[string]string{"x-correlation-id": "a b"}), "req-gw-1"},
+		"header longo demais":  {withRequestID(map[string]string{"x-correlation-id": strings.Repeat("a", logging.MaxCorrelationIDLength+1)}), "req-gw-1"},
+		"header com quebra":    {withRequestID(map[string]string{"x-correlation-id": "a\nforjado"}), "req-gw-1"},
+	}
+	for name, tc := range cases {
+		if got := correlationID(tc.req); got != tc.want {
+			t.Errorf("%s: esperava %q, obteve %q", name, tc.want, got)
+		}
+	}
+
+	if got := correlationID(postTransaction(nil)); got == "" {
+		t.Error("Sem header nem RequestID, esperava um ID gerado")
+	}
+}
+
+// ------------------------
+// 2️⃣ Propagação até o broker
+// ------------------------
+func TestHandler_PropagaCorrelationID(t *testing.T) {
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	resp, _ := handler(context.Background(), postTransaction(map[string]string{"X-Correlation-ID": "pedido-7"}))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+	if got := resp.Headers[correlationHeader]; got != "pedido-7" {
+		t.Errorf("Esperava o correlation_id na resposta, obteve %q", got)
+	}
+	if got := correlationAttributeOf(mock.published[0]); got != "pedido-7" {
+		t.Errorf("Esperava atributo correlation_id no SNS, obteve %q", got)
+	}
+}
+
+func TestHandler_ProblemUsaCorrelationIDGerado(t *testing.T) {
+	buf := useLogBuffer(t, slog.LevelInfo)
+
+	// Sem header nem RequestID o ID é gerado; a resposta de erro deve
+	// repetir o mesmo valor dos logs, não sortear outro
+	req := postTransaction(nil)
+	req.Body = "{"
+	resp, _ := handler(context.Background(), req)
+	if resp.StatusCode != 400 {
+		t.Fatalf("Esperava 400, obteve %d", resp.StatusCode)
+	}
+
+	got := resp.Headers[correlationHeader]
+	for _, line := range logLines(t, buf) {
+		if line["msg"] == "Requisição concluída" && line[logKeyCorrelationID] != got {
+			t.Errorf("Resposta com correlation_id %q, log com %v", got, line[logKeyCorrelationID])
+		}
+	}
+	if got == "" {
+		t.Error("Resposta de erro sem X-Correlation-ID")
+	}
+}
+
+func TestRelayOutbox_MantemCorrelationID(t *testing.T) {
+	store := newMemoryOutbox()
+	now := time.Now().UTC()
+	payload, _ := txevents.Encode(testEvent(testEventID1, "deposit"))
+	_ = store.Save(context.Background(), OutboxEntry{
+		EventID: testEventID1, Payload: string(payload), CorrelationID: "pedido-7",
+		CreatedAt: now, NextAttemptAt: now,
+	})
+	mock := &mockSNSClient{}
+
+	if _, err := relayOutbox(context.Background(), store, newSNSPublisher(mock, "arn:topic"), 10); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if len(mock.published) != 1 || correlationAttributeOf(mock.published[0]) != "pedido-7" {
+		t.Errorf("Relay deveria republicar com o correlation_id original")
+	}
+}
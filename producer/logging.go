@@ -0,0 +1,20 @@
+package main
+
+import "finorbit/platform/logging"
+
+// ===============================
+// Logs estruturados (slog)
+// ===============================
+//
+// Handler JSON, redação, LOG_LEVEL e correlation_id do contexto ficam em
+// finorbit/platform/logging. As chaves abaixo são as mesmas no producer, no
+// consumer e na query.
+const (
+	logKeyEventID       = "event_id"
+	logKeyUserID        = "user_id"
+	logKeyType          = "type"
+	logKeyAmount        = "amount"
+	logKeyCorrelationID = logging.KeyCorrelationID
+	logKeyLatency       = "latency_ms"
+	logKeyError         = logging.KeyError
+)
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"finorbit/platform/logging"
+)
+
+// useLogBuffer direciona os logs JSON para um buffer durante o teste.
+func useLogBuffer(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(logging.New(&buf, level))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if raw == "" {
+			continue
+		}
+		var line map[string]any
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("Linha de log não é JSON: %q", raw)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ------------------------
+// 1️⃣ Linhas do handler
+// ------------------------
+func TestLogging_HandlerRegistraCamposDoEvento(t *testing.T) {
+	buf := useLogBuffer(t, slog.LevelInfo)
+	useSNSMock(t, &mockSNSClient{})
+
+	handler(context.Background(), postTransaction(map[string]string{"X-Correlation-ID": "pedido-7"}))
+
+	var published, done map[string]any
+	for _, line := range logLines(t, buf) {
+		switch line["msg"] {
+		case "Evento publicado":
+			published = line
+		case "Requisição concluída":
+			done = line
+		}
+	}
+	if published == nil || done == nil {
+		t.Fatalf("Linhas esperadas ausentes: %s", buf.String())
+	}
+	if published[logKeyType] != "deposit" || published[logKeyAmount] != "100" || published[logKeyEventID] == nil {
+		t.Errorf("Campos do evento ausentes: %v", published)
+	}
+	if published[logKeyUserID] != "6f1c****3a4b" || published[logKeyCorrelationID] != "pedido-7" {
+		t.Errorf("Conta deveria estar mascarada e com correlation_id: %v", published)
+	}
+	if _, ok := done[logKeyLatency]; !ok || done["status"] != float64(200) {
+		t.Errorf("Esperava status e latência na conclusão: %v", done)
+	}
+	if strings.Contains(buf.String(), testAccountID) {
+		t.Error("O ID completo da conta não deveria aparecer nos logs")
+	}
+}
@@ -2,130 +2,482 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/pborman/uuid"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	txevents "finorbit/events"
+	"finorbit/platform/alerts"
+	"finorbit/platform/emf"
+	"finorbit/platform/logging"
+	"finorbit/platform/tracing"
 )
 
 // ===============================
-// Interface SNS para mock nos testes
+// Idempotência
 // ===============================
-type SNSClient interface {
-	Publish(ctx context.Context, input *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+const (
+	idempotencyHeader       = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyNamespace isola os IDs derivados de Idempotency-Key de
+// qualquer outro UUID v5 gerado com os namespaces padrão.
+var idempotencyNamespace = uuid.NewSHA1(uuid.NameSpace_URL, []byte("https://finorbit/idempotency-key"))
+
+// headerValue busca um header ignorando maiúsculas/minúsculas — o API
+// Gateway HTTP API entrega os nomes em minúsculas.
+func headerValue(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
 }
 
-var snsClient SNSClient
+// newEventID gera o identificador do evento. Quando o cliente envia
+// Idempotency-Key, o ID é derivado da conta e da chave (UUID v5), de modo
+// que retentativas com a mesma chave produzem o mesmo evento e são
+// descartadas no consumer, sem colidir com chaves de outras contas.
+func newEventID(accountID, idempotencyKey string) string {
+	if idempotencyKey == "" {
+		return uuid.NewRandom().String()
+	}
+	return uuid.NewSHA1(idempotencyNamespace, []byte(accountID+":"+idempotencyKey)).String()
+}
+
+// requestHash identifica o corpo da requisição no outbox: a mesma
+// Idempotency-Key com outro corpo é recusada em vez de confirmar o evento
+// antigo.
+func requestHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
 
 // ===============================
-// Estruturas
+// Identificação da conta
 // ===============================
-type TransactionRequest struct {
-	Amount string `json:"amount"`
-	Type   string `json:"type"`
+// defaultAccountIDClaim é a claim do JWT usada como ID da conta quando
+// ACCOUNT_ID_CLAIM não está definida (no Cognito, `sub` é um UUID).
+const defaultAccountIDClaim = "sub"
+
+var (
+	errUnauthenticated   = errors.New("requisição sem identidade autenticada")
+	errAccountIDMissing  = errors.New("account_id obrigatório")
+	errAccountIDInvalid  = errors.New("account_id inválido")
+	errAccountIDMismatch = errors.New("account_id não pertence ao usuário autenticado")
+)
+
+// authenticatedAccountID lê o ID da conta das claims do JWT validado pelo
+// authorizer do API Gateway. Retorna vazio em rotas sem autenticação.
+func authenticatedAccountID(req events.APIGatewayV2HTTPRequest) string {
+	auth := req.RequestContext.Authorizer
+	if auth == nil || auth.JWT == nil {
+		return ""
+	}
+
+	claim := os.Getenv("ACCOUNT_ID_CLAIM")
+	if claim == "" {
+		claim = defaultAccountIDClaim
+	}
+	return auth.JWT.Claims[claim]
+}
+
+// allowUnauthenticated aceita o account_id do corpo sem JWT. Só para
+// desenvolvimento local (ALLOW_UNAUTHENTICATED=true): sem a claim, qualquer
+// cliente poderia movimentar qualquer conta.
+var allowUnauthenticated bool
+
+// resolveAccountID define a conta da transação: a identidade autenticada
+// prevalece e, se o corpo também informar account_id, ambos precisam
+// coincidir. Sem a claim a requisição é recusada, exceto com
+// allowUnauthenticated, quando o account_id do corpo é obrigatório.
+func resolveAccountID(req events.APIGatewayV2HTTPRequest, requested string) (string, error) {
+	requested = strings.TrimSpace(requested)
+	authenticated := authenticatedAccountID(req)
+	if authenticated == "" && !allowUnauthenticated {
+		return "", errUnauthenticated
+	}
+
+	accountID := requested
+	if authenticated != "" {
+		if requested != "" && !strings.EqualFold(requested, authenticated) {
+			return "", errAccountIDMismatch
+		}
+		accountID = authenticated
+	}
+
+	if accountID == "" {
+		return "", errAccountIDMissing
+	}
+
+	parsed := uuid.Parse(accountID)
+	if parsed == nil {
+		return "", errAccountIDInvalid
+	}
+	return parsed.String(), nil
 }
 
-type TransactionEvent struct {
-	UserID    string          `json:"user_id"`
-	Amount    decimal.Decimal `json:"amount"`
-	Type      string          `json:"type"`
-	Timestamp string          `json:"timestamp"`
+// ===============================
+// Estruturas
+// ===============================
+type TransactionRequest struct {
+	AccountID string `json:"account_id"`
+	Amount    string `json:"amount"`
+	Type      string `json:"type"`
 }
 
 // ===============================
 // Handler da Lambda
 // ===============================
+// handler abre o span da requisição — continuando um traceparent enviado
+// pelo cliente — e registra o status da resposta.
 func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-	log.Println("🚀 FinOrbit Producer invocado!")
+	defer tracing.Flush(ctx, tracerProvider)
+	defer flushMetrics(ctx)
+	defer flushAlerts(ctx)
+	start := time.Now()
+
+	// O API Gateway entrega os headers em minúsculas, como o propagador espera
+	ctx = propagator.Extract(ctx, propagation.MapCarrier(req.Headers))
+	ctx, span := tracer().Start(ctx, "POST /transaction",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("http.request.method", req.RequestContext.HTTP.Method)),
+	)
+	defer span.End()
 
+	ctx = logging.WithCorrelationID(ctx, correlationID(req))
+	span.SetAttributes(attribute.String(correlationAttribute, logging.CorrelationIDFromContext(ctx)))
+
+	resp, err := handleTransaction(ctx, req)
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+
+	latency := time.Since(start)
+	recordRequest(resp.StatusCode, latency)
+	slog.InfoContext(ctx, "Requisição concluída",
+		"status", resp.StatusCode,
+		logKeyLatency, latency.Milliseconds(),
+	)
+	return resp, err
+}
+
+// recordRequest classifica a resposta: aceita (2xx), rejeitada por erro do
+// cliente (4xx) ou falha do serviço (5xx).
+func recordRequest(status int, latency time.Duration) {
+	name := metricRequestsAccepted
+	switch {
+	case status >= http.StatusInternalServerError:
+		name = metricRequestsFailed
+	case status >= http.StatusBadRequest:
+		name = metricRequestsRejected
+	}
+	metrics.Record(name, 1, emf.UnitCount, nil)
+	metrics.Record(metricRequestLatency, float64(latency.Milliseconds()), emf.UnitMilliseconds, nil)
+}
+
+// recordAccepted soma o evento ao volume e ao valor do seu tipo — só
+// quando ele foi de fato aceito, não em retentativas do cliente.
+func recordAccepted(event txevents.TransactionEvent) {
+	dims := emf.TypeDims(event.Type)
+	metrics.Record(metricTransactionCount, 1, emf.UnitCount, dims)
+	metrics.Record(metricTransactionAmount, event.Amount.InexactFloat64(), emf.UnitNone, dims)
+}
+
+// eventLogAttrs são os campos do evento repetidos nas linhas de log.
+func eventLogAttrs(event txevents.TransactionEvent) []any {
+	return []any{
+		logKeyEventID, event.EventID,
+		logKeyUserID, event.UserID,
+		logKeyType, event.Type,
+		logKeyAmount, event.Amount.String(),
+	}
+}
+
+func handleTransaction(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
 	// Verifica método HTTP
 	if req.RequestContext.HTTP.Method != http.MethodPost {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusMethodNotAllowed,
-			Body:       "Método não permitido",
-		}, nil
+		return problemResponse(ctx, req, http.StatusMethodNotAllowed, codeMethodNotAllowed), nil
+	}
+
+	lang := negotiateLanguage(req)
+
+	// Valida chave de idempotência (opcional)
+	idempotencyKey := strings.TrimSpace(headerValue(req.Headers, idempotencyHeader))
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return problemResponse(ctx, req, http.StatusBadRequest, codeInvalidIdempotencyKey,
+			fieldError(idempotencyHeader, codeInvalidIdempotencyKey, lang)), nil
 	}
 
 	// Decodifica corpo JSON
 	var txReq TransactionRequest
 	if err := json.Unmarshal([]byte(req.Body), &txReq); err != nil {
-		log.Printf("❌ Erro ao decodificar corpo da requisição: %v", err)
-		return events.APIGatewayV2HTTPResponse{StatusCode: 400, Body: "JSON inválido"}, nil
+		slog.WarnContext(ctx, "Corpo da requisição inválido", logKeyError, err)
+		return problemResponse(ctx, req, http.StatusBadRequest, codeInvalidJSON), nil
 	}
 
-	// Converte valor
+	// Valida campos — reporta todos os problemas de uma vez
+	var fieldErrors []FieldError
+
 	convertedAmount, err := decimal.NewFromString(txReq.Amount)
-	if err != nil {
-		log.Printf("❌ Erro ao converter valor: %v", err)
-		return events.APIGatewayV2HTTPResponse{StatusCode: 400, Body: "Valor inválido"}, nil
+	switch {
+	case err != nil:
+		slog.WarnContext(ctx, "Valor inválido", logKeyError, err)
+		fieldErrors = append(fieldErrors, fieldError("amount", codeInvalidAmount, lang))
+	case convertedAmount.LessThanOrEqual(decimal.Zero):
+		fieldErrors = append(fieldErrors, fieldError("amount", codeAmountNotPositive, lang))
+	case !txevents.HasValidScale(convertedAmount):
+		// O ledger grava 2 casas; o Postgres arredondaria o excedente
+		fieldErrors = append(fieldErrors, fieldError("amount", codeAmountTooPrecise, lang))
+	case !txevents.IsWithinMaxAmount(convertedAmount):
+		// Acima de NUMERIC(12,2) o INSERT do ledger estouraria
+		fieldErrors = append(fieldErrors, fieldError("amount", codeAmountTooLarge, lang))
 	}
 
-	// Valida campos
-	if convertedAmount.LessThanOrEqual(decimal.Zero) || (txReq.Type != "deposit" && txReq.Type != "withdraw") {
-		return events.APIGatewayV2HTTPResponse{StatusCode: 400, Body: "Campos inválidos"}, nil
+	if !txevents.IsValidType(txReq.Type) {
+		fieldErrors = append(fieldErrors, fieldError("type", codeInvalidType, lang))
+	}
+
+	// Identifica a conta
+	accountID, err := resolveAccountID(req, txReq.AccountID)
+	switch {
+	case errors.Is(err, errUnauthenticated):
+		return problemResponse(ctx, req, http.StatusUnauthorized, codeUnauthenticated), nil
+	case errors.Is(err, errAccountIDMismatch):
+		return problemResponse(ctx, req, http.StatusForbidden, codeAccountIDMismatch,
+			fieldError("account_id", codeAccountIDMismatch, lang)), nil
+	case errors.Is(err, errAccountIDMissing):
+		fieldErrors = append(fieldErrors, fieldError("account_id", codeAccountIDRequired, lang))
+	case errors.Is(err, errAccountIDInvalid):
+		fieldErrors = append(fieldErrors, fieldError("account_id", codeAccountIDInvalid, lang))
+	}
+
+	if len(fieldErrors) > 0 {
+		return problemResponse(ctx, req, http.StatusBadRequest, codeValidationFailed, fieldErrors...), nil
 	}
 
 	// Cria evento
-	event := TransactionEvent{
-		UserID:    uuid.NewUUID().String(),
+	event := txevents.TransactionEvent{
+		EventID:   newEventID(accountID, idempotencyKey),
+		UserID:    accountID,
 		Amount:    convertedAmount,
 		Type:      txReq.Type,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("finorbit.event_id", event.EventID),
+		attribute.String("finorbit.transaction.type", event.Type),
+	)
+	logger := slog.Default().With(eventLogAttrs(event)...)
 
-	// Publica no SNS
-	topicARN := os.Getenv("SNS_TOPIC_ARN")
-	if topicARN == "" {
-		return events.APIGatewayV2HTTPResponse{StatusCode: 500, Body: "Variável SNS_TOPIC_ARN não configurada"}, nil
+	if idempotencyKey == "" && recentSubmissions.Seen(submissionKey(event)) {
+		logger.WarnContext(ctx, "Possível transação duplicada")
+		alertSuspectedDuplicateSubmission(ctx, event)
 	}
 
-	data, _ := json.Marshal(event)
-	_, err = snsClient.Publish(ctx, &sns.PublishInput{
-		TopicArn: aws.String(topicARN),
-		Message:  aws.String(string(data)),
-		MessageAttributes: map[string]types.MessageAttributeValue{
-			"type": {
-				DataType:    aws.String("String"),
-				StringValue: aws.String(txReq.Type),
-			},
-		},
-	})
-	if err != nil {
-		log.Printf("❌ Erro ao publicar no SNS: %v", err)
-		return events.APIGatewayV2HTTPResponse{StatusCode: 500, Body: "Erro ao publicar mensagem"}, nil
+	// Publica no broker configurado
+	if publisher == nil {
+		logger.ErrorContext(ctx, "Publisher de eventos não configurado")
+		return problemResponse(ctx, req, http.StatusInternalServerError, codeConfigurationError), nil
 	}
 
-	log.Printf("✅ Evento publicado no SNS: %v", string(data))
-	return events.APIGatewayV2HTTPResponse{
+	data, err := txevents.Encode(event)
+	if err != nil {
+		logger.ErrorContext(ctx, "Evento fora do contrato", logKeyError, err)
+		return problemResponse(ctx, req, http.StatusInternalServerError, codePublishFailed), nil
+	}
+	now := time.Now().UTC()
+	entry := OutboxEntry{
+		EventID:       event.EventID,
+		Payload:       string(data),
+		CorrelationID: logging.CorrelationIDFromContext(ctx),
+		RequestHash:   requestHash(req.Body),
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+	if outbox != nil {
+		// O relay não republica enquanto o envio imediato está em curso;
+		// MarkFailed reagenda e MarkSent encerra o lease
+		entry.NextAttemptAt = now.Add(outboxInlineLease)
+	}
+	accepted := events.APIGatewayV2HTTPResponse{
 		StatusCode: 200,
-		Body:       fmt.Sprintf("Transação enviada para processamento: %s", txReq.Type),
-	}, nil
+		Headers:    map[string]string{correlationHeader: entry.CorrelationID},
+		Body:       fmt.Sprintf("Transação enviada para processamento: %s | event_id=%s", txReq.Type, event.EventID),
+	}
+
+	// Modo outbox: grava antes de publicar; se o broker falhar, o relay reenvia
+	if outbox != nil {
+		err := outbox.Save(ctx, entry)
+		if errors.Is(err, errOutboxDuplicate) {
+			logger.InfoContext(ctx, "Evento já registrado no outbox, retentativa do cliente")
+			return accepted, nil
+		}
+		if errors.Is(err, errIdempotencyKeyReused) {
+			logger.WarnContext(ctx, "Idempotency-Key reutilizada com outro corpo")
+			return problemResponse(ctx, req, http.StatusUnprocessableEntity, codeIdempotencyKeyReused), nil
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Erro ao gravar no outbox", logKeyError, err)
+			return problemResponse(ctx, req, http.StatusInternalServerError, codePublishFailed), nil
+		}
+		recordAccepted(event)
+
+		if err := publishEntry(ctx, publisher, entry); err != nil {
+			logger.WarnContext(ctx, "Publicação imediata falhou, relay do outbox fará nova tentativa", logKeyError, err)
+			if err := markFailed(ctx, outbox, entry, err); err != nil {
+				logger.ErrorContext(ctx, "Erro ao registrar falha no outbox", logKeyError, err)
+			}
+			return accepted, nil
+		}
+		if err := outbox.MarkSent(ctx, entry.EventID); err != nil {
+			logger.WarnContext(ctx, "Erro ao marcar evento como enviado", logKeyError, err)
+		}
+
+		logger.InfoContext(ctx, "Evento publicado")
+		return accepted, nil
+	}
+
+	if err := publishEntry(ctx, publisher, entry); err != nil {
+		logger.ErrorContext(ctx, "Erro ao publicar evento", logKeyError, err)
+		return problemResponse(ctx, req, http.StatusInternalServerError, codePublishFailed), nil
+	}
+	recordAccepted(event)
+
+	logger.InfoContext(ctx, "Evento publicado")
+	return accepted, nil
+}
+
+// ===============================
+// Relay agendado do outbox
+// ===============================
+const defaultRelayBatchSize = 100
+
+func relayHandler(ctx context.Context, _ events.CloudWatchEvent) error {
+	defer tracing.Flush(ctx, tracerProvider)
+	defer flushMetrics(ctx)
+	defer flushAlerts(ctx)
+
+	if outbox == nil {
+		return errors.New("relay do outbox requer OUTBOX_BACKEND configurado")
+	}
+
+	if publisher == nil {
+		return errors.New("publisher de eventos não configurado")
+	}
+
+	_, err := relayOutbox(ctx, outbox, publisher, defaultRelayBatchSize)
+	return err
+}
+
+// newOutboxStore escolhe o backend pelo OUTBOX_BACKEND: "dynamodb"
+// (usa OUTBOX_TABLE), "memory" (apenas desenvolvimento local) ou vazio
+// para publicar direto no broker.
+func newOutboxStore(cfg aws.Config) (OutboxStore, error) {
+	switch backend := os.Getenv("OUTBOX_BACKEND"); backend {
+	case "":
+		return nil, nil
+	case "memory":
+		return newMemoryOutbox(), nil
+	case "dynamodb":
+		table := os.Getenv("OUTBOX_TABLE")
+		if table == "" {
+			return nil, errors.New("OUTBOX_TABLE obrigatória para OUTBOX_BACKEND=dynamodb")
+		}
+		return newDynamoOutbox(dynamodb.NewFromConfig(cfg), table), nil
+	default:
+		return nil, fmt.Errorf("OUTBOX_BACKEND desconhecido: %q", backend)
+	}
 }
 
 // ===============================
 // Função main
 // ===============================
 func main() {
+	if err := logging.Setup(); err != nil {
+		logging.Fatal("Erro ao configurar logs", err)
+	}
+
 	// Carrega configuração AWS
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
-		log.Fatalf("❌ Erro ao carregar configuração AWS: %v", err)
+		logging.Fatal("Erro ao carregar configuração AWS", err)
+	}
+
+	if err := setupTracing(context.Background()); err != nil {
+		logging.Fatal("Erro ao configurar tracing", err)
+	}
+
+	allowUnauthenticated = os.Getenv("ALLOW_UNAUTHENTICATED") == "true"
+	if allowUnauthenticated {
+		slog.Warn("ALLOW_UNAUTHENTICATED ligado: account_id do corpo aceito sem JWT")
+	}
+
+	metrics, err = emf.New(metricsService)
+	if err != nil {
+		logging.Fatal("Erro ao configurar métricas", err)
+	}
+
+	alerter = alerts.New(cfg, metricsService)
+
+	format, err = newEventFormat()
+	if err != nil {
+		logging.Fatal("Erro ao configurar formato dos eventos", err)
 	}
 
-	// Inicializa client SNS real
-	snsClient = sns.NewFromConfig(cfg)
+	// Inicializa o publisher (SNS por padrão)
+	publisher, err = newPublisher(cfg)
+	if err != nil {
+		logging.Fatal("Erro ao configurar publisher", err)
+	}
+
+	outbox, err = newOutboxStore(cfg)
+	if err != nil {
+		logging.Fatal("Erro ao configurar outbox", err)
+	}
 
-	// Inicia Lambda
-	lambda.Start(handler)
+	switch os.Getenv("PRODUCER_MODE") {
+	case "outbox-relay":
+		// Relay agendado do outbox (EventBridge)
+		lambda.Start(relayHandler)
+	case "server":
+		// Servidor HTTP local, sem API Gateway
+		addr := os.Getenv("HTTP_ADDR")
+		if addr == "" {
+			addr = defaultHTTPAddr
+		}
+		err := runServer(addr)
+		if closeErr := publisher.Close(); closeErr != nil {
+			slog.Warn("Erro ao fechar publisher", logKeyError, closeErr)
+		}
+		tracing.Shutdown(context.Background(), tracerProvider)
+		if err != nil {
+			logging.Fatal("Erro no servidor HTTP", err)
+		}
+	default:
+		// Inicia Lambda
+		lambda.Start(handler)
+	}
 }
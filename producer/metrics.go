@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"finorbit/platform/emf"
+)
+
+// ===============================
+// Métricas de negócio (CloudWatch EMF)
+// ===============================
+//
+// Writer EMF, backends (METRICS_BACKEND/METRICS_NAMESPACE) e o registro em
+// memória dos testes ficam em finorbit/platform/emf.
+const metricsService = "producer"
+
+// Nomes das métricas do producer.
+const (
+	metricRequestsAccepted  = "RequestsAccepted"
+	metricRequestsRejected  = "RequestsRejected"
+	metricRequestsFailed    = "RequestsFailed"
+	metricPublishFailures   = "PublishFailures"
+	metricTransactionCount  = "TransactionCount"
+	metricTransactionAmount = "TransactionAmount"
+	metricRequestLatency    = "RequestLatency"
+)
+
+var metrics emf.Metrics = emf.Noop{}
+
+// flushMetrics publica as métricas da invocação; uma falha aqui não deve
+// mudar a resposta ao cliente.
+func flushMetrics(ctx context.Context) {
+	if err := metrics.Flush(ctx); err != nil {
+		slog.WarnContext(ctx, "Erro ao publicar métricas", logKeyError, err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"finorbit/platform/emf"
+)
+
+// useMemoryMetrics registra as métricas em memória durante o teste.
+func useMemoryMetrics(t *testing.T) *emf.Memory {
+	t.Helper()
+	m := emf.NewMemory()
+	metrics = m
+	t.Cleanup(func() { metrics = emf.Noop{} })
+	return m
+}
+
+// ------------------------
+// 1️⃣ Métricas do handler
+// ------------------------
+func TestMetrics_RequisicaoAceita(t *testing.T) {
+	m := useMemoryMetrics(t)
+	useSNSMock(t, &mockSNSClient{})
+
+	handler(context.Background(), postTransaction(nil))
+
+	if m.Sum(metricRequestsAccepted) != 1 || m.Sum(metricTransactionCount) != 1 || m.Sum(metricTransactionAmount) != 100 {
+		t.Errorf("Métricas de aceite incorretas: %+v", m.Records())
+	}
+}
+
+func TestMetrics_RequisicaoRejeitada(t *testing.T) {
+	m := useMemoryMetrics(t)
+	useSNSMock(t, &mockSNSClient{})
+
+	req := postTransaction(nil)
+	req.Body = `{"amount":"-1","type":"deposit"}`
+	handler(context.Background(), req)
+
+	if m.Sum(metricRequestsRejected) != 1 || m.Sum(metricTransactionCount) != 0 {
+		t.Errorf("Esperava 1 rejeição e nenhum volume, obteve %+v", m.Records())
+	}
+}
+
+func TestMetrics_FalhaNaPublicacao(t *testing.T) {
+	m := useMemoryMetrics(t)
+	useSNSMock(t, &mockSNSClient{shouldFail: true})
+
+	handler(context.Background(), postTransaction(nil))
+
+	if m.Sum(metricPublishFailures) != 1 || m.Sum(metricRequestsFailed) != 1 {
+		t.Errorf("Esperava falha de publicação e requisição com erro, obteve %+v", m.Records())
+	}
+}
+
+func TestMetrics_RetentativaNaoSomaVolume(t *testing.T) {
+	useOutbox(t)
+	m := useMemoryMetrics(t)
+	useSNSMock(t, &mockSNSClient{})
+
+	headers := map[string]string{"Idempotency-Key": "pedido-42"}
+	handler(context.Background(), postTransaction(headers))
+	handler(context.Background(), postTransaction(headers))
+
+	if m.Sum(metricRequestsAccepted) != 2 || m.Sum(metricTransactionCount) != 1 {
+		t.Errorf("Retentativa deveria contar como aceita sem somar volume, obteve %+v", m.Records())
+	}
+}
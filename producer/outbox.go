@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	txevents "finorbit/events"
+	"finorbit/platform/emf"
+	"finorbit/platform/logging"
+)
+
+// ===============================
+// Outbox transacional
+// ===============================
+//
+// Em modo outbox o producer grava o evento antes de responder ao cliente.
+// A publicação no broker é tentada na hora e, se falhar, o relay republica as
+// entradas pendentes com backoff até marcá-las como enviadas.
+
+const (
+	outboxPending = "pending"
+	outboxSent    = "sent"
+	outboxDead    = "dead"
+
+	// maxOutboxAttempts limita as tentativas antes de a entrada ser
+	// marcada como "dead" e exigir intervenção manual.
+	maxOutboxAttempts = 10
+
+	// outboxInlineLease reserva a entrada recém-gravada para a publicação
+	// imediata do handler: o relay só a enxerga depois desse prazo, quando
+	// MarkSent/MarkFailed já deveriam ter rodado. Se a Lambda morrer no
+	// meio do envio, o relay assume ao fim do lease.
+	outboxInlineLease = 30 * time.Second
+)
+
+// errOutboxDuplicate indica que o event_id já está no outbox — retentativa
+// do cliente com a mesma Idempotency-Key.
+var errOutboxDuplicate = errors.New("evento já registrado no outbox")
+
+// errIdempotencyKeyReused indica que o event_id já está no outbox com outro
+// corpo: a Idempotency-Key foi reaproveitada para uma transação diferente.
+var errIdempotencyKeyReused = errors.New("Idempotency-Key já usada com outro corpo")
+
+type OutboxEntry struct {
+	EventID       string
+	Payload       string
+	CorrelationID string
+	// RequestHash é o SHA-256 do corpo da requisição que criou a entrada;
+	// distingue retentativa de reuso da Idempotency-Key.
+	RequestHash   string
+	Status        string
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+}
+
+type OutboxStore interface {
+	// Save grava a entrada como pendente. Se o event_id já existir, retorna
+	// errOutboxDuplicate (mesmo RequestHash) ou errIdempotencyKeyReused.
+	Save(ctx context.Context, entry OutboxEntry) error
+	// Pending devolve até `limit` entradas pendentes cuja próxima tentativa
+	// já venceu, das mais antigas para as mais novas.
+	Pending(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error)
+	MarkSent(ctx context.Context, eventID string) error
+	// MarkFailed registra a falha e agenda a próxima tentativa (ou marca
+	// a entrada como dead ao esgotar maxOutboxAttempts).
+	MarkFailed(ctx context.Context, entry OutboxEntry, cause error, now time.Time) error
+}
+
+var outbox OutboxStore
+
+// outboxBackoff calcula a espera antes da próxima tentativa: 2^n segundos,
+// limitado a 5 minutos.
+func outboxBackoff(attempts int) time.Duration {
+	const maxDelay = 5 * time.Minute
+	if attempts >= 9 { // 2^9s já passa de 5 minutos
+		return maxDelay
+	}
+	return time.Duration(1<<attempts) * time.Second
+}
+
+// duplicateError classifica um Save com event_id repetido. Entradas
+// gravadas sem hash contam como retentativa.
+func duplicateError(storedHash, requestHash string) error {
+	if storedHash != "" && requestHash != "" && storedHash != requestHash {
+		return errIdempotencyKeyReused
+	}
+	return errOutboxDuplicate
+}
+
+// ===============================
+// Publicação de uma entrada
+// ===============================
+// publishEntry republica com o correlation_id guardado na entrada, para que
+// o relay mantenha o vínculo com a requisição original. O span de publicação
+// é o pai do processamento no consumer.
+func publishEntry(ctx context.Context, pub Publisher, entry OutboxEntry) error {
+	ctx, span := tracer().Start(logging.WithCorrelationID(ctx, entry.CorrelationID), "transactions publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.operation.type", "send"),
+			attribute.String("messaging.message.id", entry.EventID),
+		),
+	)
+	defer span.End()
+
+	event, err := txevents.Decode([]byte(entry.Payload))
+	if err != nil {
+		err = fmt.Errorf("payload inválido no outbox: %w", err)
+	} else {
+		err = pub.Publish(ctx, event)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		metrics.Record(metricPublishFailures, 1, emf.UnitCount, nil)
+	}
+	return err
+}
+
+// markFailed registra a falha no outbox e, se a entrada esgotou
+// maxOutboxAttempts, alerta o on-call.
+func markFailed(ctx context.Context, store OutboxStore, entry OutboxEntry, cause error) error {
+	if err := store.MarkFailed(ctx, entry, cause, time.Now().UTC()); err != nil {
+		return err
+	}
+	if entry.Attempts+1 >= maxOutboxAttempts {
+		alertDeadOutbox(ctx, entry, cause)
+	}
+	return nil
+}
+
+// ===============================
+// Relay — republica pendentes
+// ===============================
+type relayResult struct {
+	Sent, Failed int
+}
+
+func relayOutbox(ctx context.Context, store OutboxStore, pub Publisher, batchSize int) (relayResult, error) {
+	var result relayResult
+
+	entries, err := store.Pending(ctx, time.Now().UTC(), batchSize)
+	if err != nil {
+		return result, fmt.Errorf("erro ao listar outbox pendente: %w", err)
+	}
+
+	for _, entry := range entries {
+		entryCtx := logging.WithCorrelationID(ctx, entry.CorrelationID)
+		if err := publishEntry(entryCtx, pub, entry); err != nil {
+			slog.WarnContext(entryCtx, "Falha ao republicar evento",
+				logKeyEventID, entry.EventID, "attempt", entry.Attempts+1, logKeyError, err)
+			if markErr := markFailed(entryCtx, store, entry, err); markErr != nil {
+				slog.ErrorContext(entryCtx, "Erro ao registrar falha no outbox", logKeyEventID, entry.EventID, logKeyError, markErr)
+			}
+			result.Failed++
+			continue
+		}
+
+		if err := store.MarkSent(ctx, entry.EventID); err != nil {
+			// O evento foi publicado; no pior caso será publicado de novo e
+			// o consumer descarta a duplicata pelo event_id.
+			slog.WarnContext(entryCtx, "Erro ao marcar evento como enviado", logKeyEventID, entry.EventID, logKeyError, err)
+		}
+		result.Sent++
+	}
+
+	slog.InfoContext(ctx, "Relay do outbox concluído", "sent", result.Sent, "failed", result.Failed)
+	return result, nil
+}
+
+// ===============================
+// Implementação em memória (testes e desenvolvimento local)
+// ===============================
+type memoryOutbox struct {
+	mu      sync.Mutex
+	entries map[string]*OutboxEntry
+}
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{entries: map[string]*OutboxEntry{}}
+}
+
+func (m *memoryOutbox) Save(ctx context.Context, entry OutboxEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, exists := m.entries[entry.EventID]; exists {
+		return duplicateError(existing.RequestHash, entry.RequestHash)
+	}
+	entry.Status = outboxPending
+	m.entries[entry.EventID] = &entry
+	return nil
+}
+
+func (m *memoryOutbox) Pending(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pending []OutboxEntry
+	for _, e := range m.entries {
+		if e.Status == outboxPending && !e.NextAttemptAt.After(now) {
+			pending = append(pending, *e)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+func (m *memoryOutbox) MarkSent(ctx context.Context, eventID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[eventID]
+	if !ok {
+		return fmt.Errorf("evento %s não encontrado no outbox", eventID)
+	}
+	e.Status = outboxSent
+	return nil
+}
+
+func (m *memoryOutbox) MarkFailed(ctx context.Context, entry OutboxEntry, cause error, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[entry.EventID]
+	if !ok {
+		return fmt.Errorf("evento %s não encontrado no outbox", entry.EventID)
+	}
+	e.Attempts++
+	e.LastError = cause.Error()
+	e.NextAttemptAt = now.Add(outboxBackoff(e.Attempts))
+	if e.Attempts >= maxOutboxAttempts {
+		e.Status = outboxDead
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ===============================
+// Outbox no DynamoDB
+// ===============================
+//
+// Tabela com chave event_id e um GSI (status, next_attempt_at) para o relay
+// buscar as pendentes já vencidas. Entradas enviadas ganham expires_at
+// (TTL) e somem sozinhas depois de outboxSentRetention.
+
+// DynamoDBClient é o subconjunto do client usado pelo outbox (mock nos testes).
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+}
+
+const (
+	outboxStatusIndex   = "status-next_attempt_at-index"
+	outboxSentRetention = 7 * 24 * time.Hour
+)
+
+type dynamoOutbox struct {
+	client DynamoDBClient
+	table  string
+}
+
+func newDynamoOutbox(client DynamoDBClient, table string) *dynamoOutbox {
+	return &dynamoOutbox{client: client, table: table}
+}
+
+func numberAttr(n int64) *ddbtypes.AttributeValueMemberN {
+	return &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+func stringAttr(s string) *ddbtypes.AttributeValueMemberS {
+	return &ddbtypes.AttributeValueMemberS{Value: s}
+}
+
+func (o *dynamoOutbox) Save(ctx context.Context, entry OutboxEntry) error {
+	item := map[string]ddbtypes.AttributeValue{
+		"event_id":        stringAttr(entry.EventID),
+		"payload":         stringAttr(entry.Payload),
+		"status":          stringAttr(outboxPending),
+		"attempts":        numberAttr(0),
+		"created_at":      stringAttr(entry.CreatedAt.UTC().Format(time.RFC3339Nano)),
+		"next_attempt_at": numberAttr(entry.NextAttemptAt.UnixMilli()),
+	}
+	if entry.CorrelationID != "" {
+		item["correlation_id"] = stringAttr(entry.CorrelationID)
+	}
+	if entry.RequestHash != "" {
+		item["request_hash"] = stringAttr(entry.RequestHash)
+	}
+
+	// Com ALL_OLD a falha da condição traz o item existente, para comparar
+	// o hash sem outra leitura
+	_, err := o.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                           aws.String(o.table),
+		Item:                                item,
+		ConditionExpression:                 aws.String("attribute_not_exists(event_id)"),
+		ReturnValuesOnConditionCheckFailure: ddbtypes.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+
+	var conditionFailed *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		var stored string
+		if v, ok := conditionFailed.Item["request_hash"].(*ddbtypes.AttributeValueMemberS); ok {
+			stored = v.Value
+		}
+		return duplicateError(stored, entry.RequestHash)
+	}
+	return err
+}
+
+func (o *dynamoOutbox) Pending(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error) {
+	out, err := o.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(o.table),
+		IndexName:              aws.String(outboxStatusIndex),
+		KeyConditionExpression: aws.String("#status = :pending AND next_attempt_at <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":pending": stringAttr(outboxPending),
+			":now":     numberAttr(now.UnixMilli()),
+		},
+		Limit: aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]OutboxEntry, 0, len(out.Items))
+	for _, item := range out.Items {
+		entry, err := outboxEntryFromItem(item)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (o *dynamoOutbox) MarkSent(ctx context.Context, eventID string) error {
+	now := time.Now().UTC()
+	_, err := o.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(o.table),
+		Key:              map[string]ddbtypes.AttributeValue{"event_id": stringAttr(eventID)},
+		UpdateExpression: aws.String("SET #status = :sent, sent_at = :sent_at, expires_at = :expires_at"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":sent":       stringAttr(outboxSent),
+			":sent_at":    stringAttr(now.Format(time.RFC3339Nano)),
+			":expires_at": numberAttr(now.Add(outboxSentRetention).Unix()),
+		},
+	})
+	return err
+}
+
+func (o *dynamoOutbox) MarkFailed(ctx context.Context, entry OutboxEntry, cause error, now time.Time) error {
+	attempts := entry.Attempts + 1
+	status := outboxPending
+	if attempts >= maxOutboxAttempts {
+		status = outboxDead
+	}
+
+	_, err := o.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(o.table),
+		Key:              map[string]ddbtypes.AttributeValue{"event_id": stringAttr(entry.EventID)},
+		UpdateExpression: aws.String("SET #status = :status, attempts = :attempts, last_error = :last_error, next_attempt_at = :next"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":status":     stringAttr(status),
+			":attempts":   numberAttr(int64(attempts)),
+			":last_error": stringAttr(cause.Error()),
+			":next":       numberAttr(now.Add(outboxBackoff(attempts)).UnixMilli()),
+		},
+	})
+	return err
+}
+
+func outboxEntryFromItem(item map[string]ddbtypes.AttributeValue) (OutboxEntry, error) {
+	var entry OutboxEntry
+
+	str := func(name string) string {
+		if v, ok := item[name].(*ddbtypes.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+	num := func(name string) int64 {
+		if v, ok := item[name].(*ddbtypes.AttributeValueMemberN); ok {
+			n, _ := strconv.ParseInt(v.Value, 10, 64)
+			return n
+		}
+		return 0
+	}
+
+	entry.EventID = str("event_id")
+	entry.Payload = str("payload")
+	entry.CorrelationID = str("correlation_id")
+	entry.RequestHash = str("request_hash")
+	entry.Status = str("status")
+	entry.LastError = str("last_error")
+	entry.Attempts = int(num("attempts"))
+	entry.NextAttemptAt = time.UnixMilli(num("next_attempt_at")).UTC()
+	if entry.EventID == "" || entry.Payload == "" {
+		return entry, fmt.Errorf("item do outbox incompleto: %v", item["event_id"])
+	}
+
+	if created := str("created_at"); created != "" {
+		t, err := time.Parse(time.RFC3339Nano, created)
+		if err != nil {
+			return entry, fmt.Errorf("created_at inválido no outbox: %w", err)
+		}
+		entry.CreatedAt = t
+	}
+	return entry, nil
+}
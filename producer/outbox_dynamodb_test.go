@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type mockDynamoDBClient struct {
+	putErr  error
+	puts    []*dynamodb.PutItemInput
+	updates []*dynamodb.UpdateItemInput
+	queries []*dynamodb.QueryInput
+	items   []map[string]ddbtypes.AttributeValue
+}
+
+func (m *mockDynamoDBClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	m.puts = append(m.puts, input)
+	return &dynamodb.PutItemOutput{}, m.putErr
+}
+
+func (m *mockDynamoDBClient) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	m.updates = append(m.updates, input)
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (m *mockDynamoDBClient) Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	m.queries = append(m.queries, input)
+	return &dynamodb.QueryOutput{Items: m.items}, nil
+}
+
+func TestDynamoOutbox_SaveCondicional(t *testing.T) {
+	client := &mockDynamoDBClient{}
+	store := newDynamoOutbox(client, "outbox")
+
+	err := store.Save(context.Background(), OutboxEntry{
+		EventID:   "evt-1",
+		Payload:   `{"event_id":"evt-1"}`,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	put := client.puts[0]
+	if put.ConditionExpression == nil || *put.ConditionExpression != "attribute_not_exists(event_id)" {
+		t.Errorf("Esperava put condicional no event_id, obteve %v", put.ConditionExpression)
+	}
+	if status := put.Item["status"].(*ddbtypes.AttributeValueMemberS).Value; status != outboxPending {
+		t.Errorf("Esperava status %q, obteve %q", outboxPending, status)
+	}
+}
+
+func TestDynamoOutbox_SaveDuplicado(t *testing.T) {
+	client := &mockDynamoDBClient{putErr: &ddbtypes.ConditionalCheckFailedException{}}
+	store := newDynamoOutbox(client, "outbox")
+
+	err := store.Save(context.Background(), OutboxEntry{EventID: "evt-1", Payload: `{}`})
+	if !errors.Is(err, errOutboxDuplicate) {
+		t.Errorf("Esperava errOutboxDuplicate, obteve %v", err)
+	}
+}
+
+func TestDynamoOutbox_SaveChaveReutilizada(t *testing.T) {
+	client := &mockDynamoDBClient{putErr: &ddbtypes.ConditionalCheckFailedException{
+		Item: map[string]ddbtypes.AttributeValue{"request_hash": stringAttr("hash-original")},
+	}}
+	store := newDynamoOutbox(client, "outbox")
+
+	err := store.Save(context.Background(), OutboxEntry{EventID: "evt-1", Payload: `{}`, RequestHash: "hash-novo"})
+	if !errors.Is(err, errIdempotencyKeyReused) {
+		t.Errorf("Esperava errIdempotencyKeyReused, obteve %v", err)
+	}
+	if client.puts[0].ReturnValuesOnConditionCheckFailure != ddbtypes.ReturnValuesOnConditionCheckFailureAllOld {
+		t.Error("Esperava o item antigo na falha da condição para comparar o hash")
+	}
+
+	err = store.Save(context.Background(), OutboxEntry{EventID: "evt-1", Payload: `{}`, RequestHash: "hash-original"})
+	if !errors.Is(err, errOutboxDuplicate) {
+		t.Errorf("Mesmo corpo deveria ser retentativa, obteve %v", err)
+	}
+}
+
+func TestDynamoOutbox_PendingConverteItens(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	client := &mockDynamoDBClient{items: []map[string]ddbtypes.AttributeValue{{
+		"event_id":        stringAttr("evt-1"),
+		"payload":         stringAttr(`{}`),
+		"status":          stringAttr(outboxPending),
+		"attempts":        numberAttr(3),
+		"created_at":      stringAttr(created.Format(time.RFC3339Nano)),
+		"next_attempt_at": numberAttr(created.UnixMilli()),
+		"correlation_id":  stringAttr("pedido-7"),
+	}}}
+	store := newDynamoOutbox(client, "outbox")
+
+	entries, err := store.Pending(context.Background(), time.Now(), 25)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Esperava 1 entrada, obteve %d", len(entries))
+	}
+
+	e := entries[0]
+	if e.EventID != "evt-1" || e.Attempts != 3 || !e.CreatedAt.Equal(created) || e.CorrelationID != "pedido-7" {
+		t.Errorf("Entrada convertida incorretamente: %+v", e)
+	}
+	if *client.queries[0].IndexName != outboxStatusIndex {
+		t.Errorf("Esperava consulta no índice %q", outboxStatusIndex)
+	}
+}
+
+func TestDynamoOutbox_MarkFailedEsgotaTentativas(t *testing.T) {
+	client := &mockDynamoDBClient{}
+	store := newDynamoOutbox(client, "outbox")
+
+	entry := OutboxEntry{EventID: "evt-1", Attempts: maxOutboxAttempts - 1}
+	if err := store.MarkFailed(context.Background(), entry, errors.New("falha"), time.Now()); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	status := client.updates[0].ExpressionAttributeValues[":status"].(*ddbtypes.AttributeValueMemberS).Value
+	if status != outboxDead {
+		t.Errorf("Esperava status %q, obteve %q", outboxDead, status)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	txevents "finorbit/events"
+	"finorbit/platform/alerts"
+)
+
+// useOutbox liga o modo outbox com um store em memória durante o teste.
+func useOutbox(t *testing.T) *memoryOutbox {
+	t.Helper()
+	store := newMemoryOutbox()
+	outbox = store
+	t.Cleanup(func() { outbox = nil })
+	return store
+}
+
+// ------------------------
+// 1️⃣ Handler em modo outbox
+// ------------------------
+func TestOutbox_PublicaEMarcaComoEnviado(t *testing.T) {
+	store := useOutbox(t)
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	resp, _ := handler(context.Background(), postTransaction(nil))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+	if len(mock.published) != 1 {
+		t.Fatalf("Esperava 1 publicação, obteve %d", len(mock.published))
+	}
+
+	event := publishedEvent(t, mock.published[0])
+	if got := store.entries[event.EventID].Status; got != outboxSent {
+		t.Errorf("Esperava status %q, obteve %q", outboxSent, got)
+	}
+}
+
+func TestOutbox_FalhaNoSNSAindaAceita(t *testing.T) {
+	store := useOutbox(t)
+	useSNSMock(t, &mockSNSClient{shouldFail: true})
+
+	resp, _ := handler(context.Background(), postTransaction(nil))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200 com o evento guardado no outbox, obteve %d", resp.StatusCode)
+	}
+
+	if len(store.entries) != 1 {
+		t.Fatalf("Esperava 1 entrada no outbox, obteve %d", len(store.entries))
+	}
+	for _, e := range store.entries {
+		if e.Status != outboxPending || e.Attempts != 1 {
+			t.Errorf("Esperava entrada pendente com 1 tentativa, obteve %q/%d", e.Status, e.Attempts)
+		}
+	}
+}
+
+// leasePublisher roda o relay no meio da publicação imediata, como uma
+// execução agendada que coincide com a requisição.
+type leasePublisher struct {
+	store   *memoryOutbox
+	pending int
+}
+
+func (p *leasePublisher) Publish(ctx context.Context, _ txevents.TransactionEvent) error {
+	entries, _ := p.store.Pending(ctx, time.Now().UTC(), 10)
+	p.pending = len(entries)
+	return errors.New("broker fora do ar")
+}
+
+func (p *leasePublisher) Close() error { return nil }
+
+func TestOutbox_RelayNaoDisputaEnvioImediato(t *testing.T) {
+	store := useOutbox(t)
+	pub := &leasePublisher{store: store}
+	publisher = pub
+	t.Cleanup(func() { publisher = nil })
+
+	resp, _ := handler(context.Background(), postTransaction(nil))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+	if pub.pending != 0 {
+		t.Errorf("Relay enxergou %d entradas durante o envio imediato", pub.pending)
+	}
+
+	// A falha encerra o lease: a entrada volta ao relay após o backoff
+	if pending, _ := store.Pending(context.Background(), time.Now().UTC().Add(outboxBackoff(1)), 10); len(pending) != 1 {
+		t.Errorf("Esperava a entrada pendente após o backoff, obteve %d", len(pending))
+	}
+}
+
+func TestOutbox_RetentativaDoClienteNaoRepublica(t *testing.T) {
+	useOutbox(t)
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	headers := map[string]string{"Idempotency-Key": "pedido-42"}
+	for i := 0; i < 2; i++ {
+		resp, _ := handler(context.Background(), postTransaction(headers))
+		if resp.StatusCode != 200 {
+			t.Fatalf("Tentativa %d: esperava 200, obteve %d", i+1, resp.StatusCode)
+		}
+	}
+
+	if len(mock.published) != 1 {
+		t.Errorf("Esperava 1 publicação para a mesma Idempotency-Key, obteve %d", len(mock.published))
+	}
+}
+
+func TestOutbox_MesmaChaveComOutroCorpoRecusada(t *testing.T) {
+	useOutbox(t)
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	headers := map[string]string{"Idempotency-Key": "pedido-42"}
+	if resp, _ := handler(context.Background(), postTransaction(headers)); resp.StatusCode != 200 {
+		t.Fatalf("Primeira requisição: esperava 200, obteve %d", resp.StatusCode)
+	}
+
+	req := postTransaction(headers)
+	req.Body = `{"account_id":"` + testAccountID + `","amount":"250","type":"deposit"}`
+	resp, _ := handler(context.Background(), req)
+	if resp.StatusCode != 422 {
+		t.Fatalf("Esperava 422 para chave reutilizada, obteve %d", resp.StatusCode)
+	}
+	if problem := decodeProblem(t, resp); problem.Code != codeIdempotencyKeyReused {
+		t.Errorf("Esperava código %s, obteve %+v", codeIdempotencyKeyReused, problem)
+	}
+	if len(mock.published) != 1 {
+		t.Errorf("Corpo novo não deveria ser publicado, obteve %d publicações", len(mock.published))
+	}
+}
+
+// ------------------------
+// 2️⃣ Relay
+// ------------------------
+func TestRelayOutbox_RepublicaPendentes(t *testing.T) {
+	store := newMemoryOutbox()
+	now := time.Now().UTC()
+	for _, id := range []string{testEventID1, testEventID2} {
+		payload, _ := txevents.Encode(testEvent(id, "deposit"))
+		_ = store.Save(context.Background(), OutboxEntry{EventID: id, Payload: string(payload), CreatedAt: now, NextAttemptAt: now})
+	}
+	pub := newMemoryPublisher()
+
+	result, err := relayOutbox(context.Background(), store, pub, 10)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if result.Sent != 2 || result.Failed != 0 {
+		t.Errorf("Esperava 2 enviados, obteve %+v", result)
+	}
+	if got := len(pub.Events()); got != 2 {
+		t.Errorf("Esperava 2 eventos publicados, obteve %d", got)
+	}
+	if pending, _ := store.Pending(context.Background(), time.Now().UTC(), 10); len(pending) != 0 {
+		t.Errorf("Esperava outbox vazio, restaram %d", len(pending))
+	}
+}
+
+func TestRelayOutbox_FalhaAgendaBackoff(t *testing.T) {
+	store := newMemoryOutbox()
+	now := time.Now().UTC()
+	payload, _ := txevents.Encode(testEvent(testEventID1, "deposit"))
+	_ = store.Save(context.Background(), OutboxEntry{EventID: testEventID1, Payload: string(payload), CreatedAt: now, NextAttemptAt: now})
+	pub := newSNSPublisher(&mockSNSClient{shouldFail: true}, "arn:topic")
+
+	result, err := relayOutbox(context.Background(), store, pub, 10)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if result.Failed != 1 {
+		t.Errorf("Esperava 1 falha, obteve %+v", result)
+	}
+
+	// A próxima tentativa só vence depois do backoff
+	if pending, _ := store.Pending(context.Background(), time.Now().UTC(), 10); len(pending) != 0 {
+		t.Errorf("Entrada não deveria estar vencida antes do backoff")
+	}
+	if pending, _ := store.Pending(context.Background(), time.Now().UTC().Add(outboxBackoff(1)), 10); len(pending) != 1 {
+		t.Errorf("Entrada deveria voltar a ficar pendente após o backoff")
+	}
+}
+
+func TestRelayOutbox_UltimaTentativaAlerta(t *testing.T) {
+	rec := useRecordingAlerter(t)
+	store := newMemoryOutbox()
+	now := time.Now().UTC()
+	payload, _ := txevents.Encode(testEvent(testEventID1, "deposit"))
+	_ = store.Save(context.Background(), OutboxEntry{EventID: testEventID1, Payload: string(payload), CorrelationID: "corr-1", CreatedAt: now, NextAttemptAt: now})
+	store.entries[testEventID1].Attempts = maxOutboxAttempts - 2
+	pub := newSNSPublisher(&mockSNSClient{shouldFail: true}, "arn:topic")
+
+	// Penúltima tentativa: a entrada segue pendente, sem alerta
+	relayOutbox(context.Background(), store, pub, 10)
+	if len(rec.alerts) != 0 {
+		t.Fatalf("Não deveria alertar antes de esgotar as tentativas, obteve %+v", rec.alerts)
+	}
+
+	store.entries[testEventID1].NextAttemptAt = now
+	relayOutbox(context.Background(), store, pub, 10)
+	if got := store.entries[testEventID1].Status; got != outboxDead {
+		t.Fatalf("Esperava status %q, obteve %q", outboxDead, got)
+	}
+	if len(rec.alerts) != 1 || rec.alerts[0].Kind != alertDeadOutboxEntry || rec.alerts[0].Severity != alerts.SeverityCritical {
+		t.Fatalf("Esperava 1 alerta crítico de outbox esgotado, obteve %+v", rec.alerts)
+	}
+	details := rec.alerts[0].Details
+	if details[logKeyEventID] != testEventID1 || details["attempts"] != fmt.Sprint(maxOutboxAttempts) || details[logKeyCorrelationID] != "corr-1" {
+		t.Errorf("Alerta sem a identificação da entrada: %+v", details)
+	}
+}
+
+func TestMemoryOutbox_EsgotaTentativas(t *testing.T) {
+	store := newMemoryOutbox()
+	entry := OutboxEntry{EventID: "evt-1", Payload: `{}`}
+	_ = store.Save(context.Background(), entry)
+
+	for i := 0; i < maxOutboxAttempts; i++ {
+		_ = store.MarkFailed(context.Background(), entry, errors.New("falha"), time.Now())
+	}
+
+	if got := store.entries["evt-1"].Status; got != outboxDead {
+		t.Errorf("Esperava status %q após %d tentativas, obteve %q", outboxDead, maxOutboxAttempts, got)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  2 * time.Second,
+		4:  16 * time.Second,
+		9:  5 * time.Minute,
+		20: 5 * time.Minute,
+	}
+	for attempts, want := range cases {
+		if got := outboxBackoff(attempts); got != want {
+			t.Errorf("outboxBackoff(%d) = %v, esperava %v", attempts, got, want)
+		}
+	}
+}
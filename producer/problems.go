@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"finorbit/platform/logging"
+)
+
+// ===============================
+// Erros estruturados (RFC 7807)
+// ===============================
+const problemContentType = "application/problem+json"
+
+// Códigos estáveis — clientes devem depender deles, nunca das mensagens.
+const (
+	codeMethodNotAllowed      = "method_not_allowed"
+	codeInvalidJSON           = "invalid_json"
+	codeValidationFailed      = "validation_failed"
+	codeInvalidIdempotencyKey = "invalid_idempotency_key"
+	codeIdempotencyKeyReused  = "idempotency_key_reused"
+	codeInvalidAmount         = "invalid_amount"
+	codeAmountNotPositive     = "amount_not_positive"
+	codeAmountTooPrecise      = "amount_too_precise"
+	codeAmountTooLarge        = "amount_too_large"
+	codeInvalidType           = "invalid_type"
+	codeUnauthenticated       = "unauthenticated"
+	codeAccountIDRequired     = "account_id_required"
+	codeAccountIDInvalid      = "account_id_invalid"
+	codeAccountIDMismatch     = "account_id_mismatch"
+	codeConfigurationError    = "configuration_error"
+	codePublishFailed         = "publish_failed"
+)
+
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Code     string       `json:"code"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ===============================
+// Mensagens localizadas
+// ===============================
+const (
+	langPT = "pt-BR"
+	langEN = "en"
+)
+
+var problemMessages = map[string]map[string]string{
+	codeMethodNotAllowed: {
+		langPT: "Método não permitido",
+		langEN: "Method not allowed",
+	},
+	codeInvalidJSON: {
+		langPT: "JSON inválido",
+		langEN: "Malformed JSON body",
+	},
+	codeValidationFailed: {
+		langPT: "Campos inválidos",
+		langEN: "Invalid fields",
+	},
+	codeInvalidIdempotencyKey: {
+		langPT: "Idempotency-Key deve ter até 255 caracteres",
+		langEN: "Idempotency-Key must be at most 255 characters",
+	},
+	codeIdempotencyKeyReused: {
+		langPT: "Idempotency-Key já usada com outro corpo",
+		langEN: "Idempotency-Key was already used with a different request body",
+	},
+	codeInvalidAmount: {
+		langPT: "Valor inválido",
+		langEN: "Amount is not a valid decimal number",
+	},
+	codeAmountNotPositive: {
+		langPT: "Valor deve ser maior que zero",
+		langEN: "Amount must be greater than zero",
+	},
+	codeAmountTooPrecise: {
+		langPT: "Valor deve ter no máximo 2 casas decimais",
+		langEN: "Amount must have at most 2 decimal places",
+	},
+	codeAmountTooLarge: {
+		langPT: "Valor deve ser no máximo 9999999999.99",
+		langEN: "Amount must be at most 9999999999.99",
+	},
+	codeInvalidType: {
+		langPT: "Tipo deve ser deposit ou withdraw",
+		langEN: "Type must be deposit or withdraw",
+	},
+	codeUnauthenticated: {
+		langPT: "Autenticação obrigatória",
+		langEN: "Authentication required",
+	},
+	codeAccountIDRequired: {
+		langPT: "account_id obrigatório",
+		langEN: "account_id is required",
+	},
+	codeAccountIDInvalid: {
+		langPT: "account_id deve ser um UUID",
+		langEN: "account_id must be a UUID",
+	},
+	codeAccountIDMismatch: {
+		langPT: "account_id não pertence ao usuário autenticado",
+		langEN: "account_id does not belong to the authenticated user",
+	},
+	codeConfigurationError: {
+		langPT: "Serviço mal configurado",
+		langEN: "Service misconfigured",
+	},
+	codePublishFailed: {
+		langPT: "Erro ao publicar mensagem",
+		langEN: "Failed to publish the transaction",
+	},
+}
+
+// negotiateLanguage escolhe o idioma pelo Accept-Language; o padrão é pt-BR.
+func negotiateLanguage(req events.APIGatewayV2HTTPRequest) string {
+	for _, part := range strings.Split(headerValue(req.Headers, "Accept-Language"), ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		switch {
+		case strings.HasPrefix(tag, "pt"):
+			return langPT
+		case strings.HasPrefix(tag, "en"):
+			return langEN
+		}
+	}
+	return langPT
+}
+
+func problemMessage(code, lang string) string {
+	return problemMessages[code][lang]
+}
+
+func fieldError(field, code, lang string) FieldError {
+	return FieldError{Field: field, Code: code, Message: problemMessage(code, lang)}
+}
+
+// problemResponse monta o corpo application/problem+json. O RequestID do
+// API Gateway vai em `instance` para facilitar o rastreio nos logs, e o
+// X-Correlation-ID é o já resolvido pelo handler e guardado em ctx.
+func problemResponse(ctx context.Context, req events.APIGatewayV2HTTPRequest, status int, code string, fields ...FieldError) events.APIGatewayV2HTTPResponse {
+	lang := negotiateLanguage(req)
+	problem := Problem{
+		Type:     "urn:finorbit:problem:" + code,
+		Title:    problemMessage(code, lang),
+		Status:   status,
+		Code:     code,
+		Instance: req.RequestContext.RequestID,
+		Errors:   fields,
+	}
+	if len(fields) == 1 {
+		problem.Detail = fields[0].Message
+	}
+
+	headers := map[string]string{
+		"Content-Type":     problemContentType,
+		"Content-Language": lang,
+		correlationHeader:  logging.CorrelationIDFromContext(ctx),
+	}
+	if status == http.StatusUnauthorized {
+		headers["WWW-Authenticate"] = "Bearer"
+	}
+
+	body, _ := json.Marshal(problem)
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: status,
+		Headers:    headers,
+		Body:       string(body),
+	}
+}
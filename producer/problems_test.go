@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func decodeProblem(t *testing.T, resp events.APIGatewayV2HTTPResponse) Problem {
+	t.Helper()
+	if ct := resp.Headers["Content-Type"]; ct != problemContentType {
+		t.Errorf("Esperava Content-Type %s, obteve %q", problemContentType, ct)
+	}
+	var problem Problem
+	if err := json.Unmarshal([]byte(resp.Body), &problem); err != nil {
+		t.Fatalf("Corpo não é problem+json: %v (%s)", err, resp.Body)
+	}
+	if problem.Status != resp.StatusCode {
+		t.Errorf("status do corpo (%d) difere do HTTP (%d)", problem.Status, resp.StatusCode)
+	}
+	return problem
+}
+
+func TestProblem_ReportaTodosOsCampos(t *testing.T) {
+	body, _ := json.Marshal(map[string]string{"account_id": "x", "amount": "-1", "type": "transfer"})
+	req := postTransaction(nil)
+	req.Body = string(body)
+	req.RequestContext.RequestID = "req-123"
+
+	resp, _ := handler(context.Background(), req)
+	problem := decodeProblem(t, resp)
+
+	if problem.Code != codeValidationFailed || problem.Instance != "req-123" {
+		t.Errorf("Problem inesperado: %+v", problem)
+	}
+
+	got := map[string]string{}
+	for _, e := range problem.Errors {
+		got[e.Field] = e.Code
+	}
+	want := map[string]string{"amount": codeAmountNotPositive, "type": codeInvalidType, "account_id": codeAccountIDInvalid}
+	for field, code := range want {
+		if got[field] != code {
+			t.Errorf("Campo %s: esperava código %s, obteve %q", field, code, got[field])
+		}
+	}
+}
+
+func TestProblem_ValorComMaisDeDuasCasas(t *testing.T) {
+	body, _ := json.Marshal(map[string]string{"account_id": testAccountID, "amount": "10.005", "type": "deposit"})
+	req := postTransaction(nil)
+	req.Body = string(body)
+
+	problem := decodeProblem(t, mustHandle(t, req))
+	if len(problem.Errors) != 1 || problem.Errors[0].Code != codeAmountTooPrecise {
+		t.Errorf("Esperava apenas amount_too_precise, obteve %+v", problem)
+	}
+}
+
+func TestProblem_ValorAcimaDoMaximo(t *testing.T) {
+	useSNSMock(t, &mockSNSClient{})
+
+	cases := map[string]int{"9999999999.99": 200, "10000000000.00": 400, "10000000000": 400}
+	for amount, want := range cases {
+		body, _ := json.Marshal(map[string]string{"account_id": testAccountID, "amount": amount, "type": "deposit"})
+		req := postTransaction(nil)
+		req.Body = string(body)
+
+		resp := mustHandle(t, req)
+		if resp.StatusCode != want {
+			t.Errorf("amount %s: esperava %d, obteve %d", amount, want, resp.StatusCode)
+			continue
+		}
+		if want == 400 {
+			if problem := decodeProblem(t, resp); len(problem.Errors) != 1 || problem.Errors[0].Code != codeAmountTooLarge {
+				t.Errorf("amount %s: esperava apenas amount_too_large, obteve %+v", amount, problem)
+			}
+		}
+	}
+}
+
+func TestProblem_ValorNaoNumerico(t *testing.T) {
+	body, _ := json.Marshal(map[string]string{"account_id": testAccountID, "amount": "dez", "type": "deposit"})
+	req := postTransaction(nil)
+	req.Body = string(body)
+
+	problem := decodeProblem(t, mustHandle(t, req))
+	if len(problem.Errors) != 1 || problem.Errors[0].Code != codeInvalidAmount || problem.Detail == "" {
+		t.Errorf("Esperava apenas invalid_amount com detail, obteve %+v", problem)
+	}
+}
+
+func TestProblem_Localizado(t *testing.T) {
+	req := events.APIGatewayV2HTTPRequest{
+		Headers: map[string]string{"accept-language": "en-US,en;q=0.9"},
+		Body:    "{invalid",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "POST"},
+		},
+	}
+
+	resp := mustHandle(t, req)
+	problem := decodeProblem(t, resp)
+	if problem.Code != codeInvalidJSON || problem.Title != "Malformed JSON body" {
+		t.Errorf("Esperava mensagem em inglês, obteve %+v", problem)
+	}
+	if resp.Headers["Content-Language"] != langEN {
+		t.Errorf("Esperava Content-Language en, obteve %q", resp.Headers["Content-Language"])
+	}
+}
+
+func TestProblem_MetodoNaoPermitido(t *testing.T) {
+	req := events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+		},
+	}
+
+	problem := decodeProblem(t, mustHandle(t, req))
+	if problem.Code != codeMethodNotAllowed || problem.Title != "Método não permitido" {
+		t.Errorf("Problem inesperado: %+v", problem)
+	}
+}
+
+func TestProblemMessages_TodosOsCodigosTraduzidos(t *testing.T) {
+	for code, messages := range problemMessages {
+		for _, lang := range []string{langPT, langEN} {
+			if messages[lang] == "" {
+				t.Errorf("Código %s sem mensagem em %s", code, lang)
+			}
+		}
+	}
+}
+
+func mustHandle(t *testing.T, req events.APIGatewayV2HTTPRequest) events.APIGatewayV2HTTPResponse {
+	t.Helper()
+	resp, err := handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Handler retornou erro: %v", err)
+	}
+	return resp
+}
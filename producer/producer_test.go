@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+
+	txevents "finorbit/events"
 )
 
+const testAccountID = "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b"
+
 // ------------------------
 // 1️⃣ Método inválido
 // ------------------------
@@ -32,7 +37,7 @@ func TestInvalidMethod(t *testing.T) {
 // 2️⃣ JSON válido
 // ------------------------
 func TestValidTransactionParsing(t *testing.T) {
-	reqBody := map[string]string{"amount": "150.25", "type": "deposit"}
+	reqBody := map[string]string{"account_id": testAccountID, "amount": "150.25", "type": "deposit"}
 	body, _ := json.Marshal(reqBody)
 
 	req := events.APIGatewayV2HTTPRequest{
@@ -114,10 +119,10 @@ func TestInvalidType(t *testing.T) {
 }
 
 // ------------------------
-// 6️⃣ SNS_TOPIC_ARN não configurado
+// 6️⃣ Publisher não configurado
 // ------------------------
-func TestMissingSNSTopic(t *testing.T) {
-	reqBody := map[string]string{"amount": "50", "type": "deposit"}
+func TestMissingPublisher(t *testing.T) {
+	reqBody := map[string]string{"account_id": testAccountID, "amount": "50", "type": "deposit"}
 	body, _ := json.Marshal(reqBody)
 
 	req := events.APIGatewayV2HTTPRequest{
@@ -129,11 +134,11 @@ func TestMissingSNSTopic(t *testing.T) {
 		},
 	}
 
-	// Garante que a variável não está definida
-	t.Setenv("SNS_TOPIC_ARN", "")
+	// Garante que nenhum publisher está configurado
+	publisher = nil
 	resp, _ := handler(context.Background(), req)
 	if resp.StatusCode != 500 {
-		t.Errorf("Esperava 500 quando SNS_TOPIC_ARN não configurado, obteve %d", resp.StatusCode)
+		t.Errorf("Esperava 500 quando o publisher não está configurado, obteve %d", resp.StatusCode)
 	}
 }
 
@@ -142,17 +147,26 @@ func TestMissingSNSTopic(t *testing.T) {
 // ------------------------
 type mockSNSClient struct {
 	shouldFail bool
+	published  []*sns.PublishInput
 }
 
 func (m *mockSNSClient) Publish(ctx context.Context, input *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	m.published = append(m.published, input)
 	if m.shouldFail {
 		return nil, errors.New("erro simulado SNS")
 	}
 	return &sns.PublishOutput{}, nil
 }
 
+// useSNSMock publica via SNS com o client mockado durante o teste.
+func useSNSMock(t *testing.T, mock *mockSNSClient) {
+	t.Helper()
+	publisher = newSNSPublisher(mock, "arn:aws:sns:us-east-1:123456789012:test-topic")
+	t.Cleanup(func() { publisher = nil })
+}
+
 func TestSNSPublishSuccess(t *testing.T) {
-	reqBody := map[string]string{"amount": "100", "type": "deposit"}
+	reqBody := map[string]string{"account_id": testAccountID, "amount": "100", "type": "deposit"}
 	body, _ := json.Marshal(reqBody)
 
 	req := events.APIGatewayV2HTTPRequest{
@@ -164,8 +178,7 @@ func TestSNSPublishSuccess(t *testing.T) {
 		},
 	}
 
-	t.Setenv("SNS_TOPIC_ARN", "arn:aws:sns:us-east-1:123456789012:test-topic")
-	snsClient = &mockSNSClient{shouldFail: false}
+	useSNSMock(t, &mockSNSClient{shouldFail: false})
 
 	resp, _ := handler(context.Background(), req)
 	if resp.StatusCode != 200 {
@@ -174,7 +187,7 @@ func TestSNSPublishSuccess(t *testing.T) {
 }
 
 func TestSNSPublishFails(t *testing.T) {
-	reqBody := map[string]string{"amount": "100", "type": "deposit"}
+	reqBody := map[string]string{"account_id": testAccountID, "amount": "100", "type": "deposit"}
 	body, _ := json.Marshal(reqBody)
 
 	req := events.APIGatewayV2HTTPRequest{
@@ -186,11 +199,184 @@ func TestSNSPublishFails(t *testing.T) {
 		},
 	}
 
-	t.Setenv("SNS_TOPIC_ARN", "arn:aws:sns:us-east-1:123456789012:test-topic")
-	snsClient = &mockSNSClient{shouldFail: true}
+	useSNSMock(t, &mockSNSClient{shouldFail: true})
 
 	resp, _ := handler(context.Background(), req)
 	if resp.StatusCode != 500 {
 		t.Errorf("Esperava 500 quando Publish falha, obteve %d", resp.StatusCode)
 	}
 }
+
+// ------------------------
+// 8️⃣ Idempotência
+// ------------------------
+func publishedEvent(t *testing.T, input *sns.PublishInput) txevents.TransactionEvent {
+	t.Helper()
+	var event txevents.TransactionEvent
+	if err := json.Unmarshal([]byte(*input.Message), &event); err != nil {
+		t.Fatalf("Mensagem publicada não é um TransactionEvent: %v", err)
+	}
+	return event
+}
+
+// Os testes montam requisições sem authorizer; os que exercitam a
+// autenticação desligam allowUnauthenticated explicitamente.
+func init() {
+	allowUnauthenticated = true
+}
+
+// requireAuthentication liga a exigência de JWT durante o teste.
+func requireAuthentication(t *testing.T) {
+	t.Helper()
+	allowUnauthenticated = false
+	t.Cleanup(func() { allowUnauthenticated = true })
+}
+
+func postTransaction(headers map[string]string) events.APIGatewayV2HTTPRequest {
+	body, _ := json.Marshal(map[string]string{"account_id": testAccountID, "amount": "100", "type": "deposit"})
+	return events.APIGatewayV2HTTPRequest{
+		Headers: headers,
+		Body:    string(body),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+			},
+		},
+	}
+}
+
+func TestIdempotencyKeyGeneratesStableEventID(t *testing.T) {
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	for i := 0; i < 2; i++ {
+		resp, _ := handler(context.Background(), postTransaction(map[string]string{"idempotency-key": "pedido-42"}))
+		if resp.StatusCode != 200 {
+			t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+		}
+	}
+
+	first := publishedEvent(t, mock.published[0])
+	second := publishedEvent(t, mock.published[1])
+	if first.EventID == "" || first.EventID != second.EventID {
+		t.Errorf("Esperava o mesmo event_id para a mesma Idempotency-Key, obteve %q e %q", first.EventID, second.EventID)
+	}
+	if got := *mock.published[0].MessageAttributes["event_id"].StringValue; got != first.EventID {
+		t.Errorf("Atributo event_id divergente da mensagem: %q != %q", got, first.EventID)
+	}
+}
+
+func TestEventIDIsUniqueWithoutIdempotencyKey(t *testing.T) {
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	handler(context.Background(), postTransaction(nil))
+	handler(context.Background(), postTransaction(nil))
+
+	first := publishedEvent(t, mock.published[0])
+	second := publishedEvent(t, mock.published[1])
+	if first.EventID == second.EventID {
+		t.Errorf("Esperava event_id distintos sem Idempotency-Key, obteve %q duas vezes", first.EventID)
+	}
+}
+
+func TestIdempotencyKeyTooLong(t *testing.T) {
+	key := strings.Repeat("x", maxIdempotencyKeyLength+1)
+	resp, _ := handler(context.Background(), postTransaction(map[string]string{"Idempotency-Key": key}))
+	if resp.StatusCode != 400 {
+		t.Errorf("Esperava 400 para Idempotency-Key longa demais, obteve %d", resp.StatusCode)
+	}
+}
+
+// ------------------------
+// 9️⃣ Conta da transação
+// ------------------------
+func withJWTClaims(req events.APIGatewayV2HTTPRequest, claims map[string]string) events.APIGatewayV2HTTPRequest {
+	req.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+		JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{Claims: claims},
+	}
+	return req
+}
+
+func TestAccountIDFromBody(t *testing.T) {
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	resp, _ := handler(context.Background(), postTransaction(nil))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+
+	event := publishedEvent(t, mock.published[0])
+	if event.UserID != testAccountID {
+		t.Errorf("Esperava conta %s, obteve %s", testAccountID, event.UserID)
+	}
+	if event.EventID == event.UserID {
+		t.Error("event_id não deveria reutilizar o ID da conta")
+	}
+}
+
+func TestAccountIDFromJWTClaim(t *testing.T) {
+	t.Setenv("ACCOUNT_ID_CLAIM", "custom:account_id")
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	body, _ := json.Marshal(map[string]string{"amount": "100", "type": "deposit"})
+	req := postTransaction(nil)
+	req.Body = string(body)
+	req = withJWTClaims(req, map[string]string{"sub": "outro", "custom:account_id": testAccountID})
+
+	resp, _ := handler(context.Background(), req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+	if event := publishedEvent(t, mock.published[0]); event.UserID != testAccountID {
+		t.Errorf("Esperava conta da claim %s, obteve %s", testAccountID, event.UserID)
+	}
+}
+
+func TestAccountIDMismatchWithJWT(t *testing.T) {
+	req := withJWTClaims(postTransaction(nil), map[string]string{"sub": "0a0b0c0d-0000-4000-8000-000000000000"})
+
+	resp, _ := handler(context.Background(), req)
+	if resp.StatusCode != 403 {
+		t.Errorf("Esperava 403 quando account_id diverge do JWT, obteve %d", resp.StatusCode)
+	}
+}
+
+func TestAccountIDSemJWTRecusado(t *testing.T) {
+	requireAuthentication(t)
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	resp, _ := handler(context.Background(), postTransaction(nil))
+	if resp.StatusCode != 401 || resp.Headers["WWW-Authenticate"] != "Bearer" {
+		t.Errorf("Esperava 401 com WWW-Authenticate sem claim de conta, obteve %d %v", resp.StatusCode, resp.Headers)
+	}
+	if len(mock.published) != 0 {
+		t.Errorf("Nada deveria ser publicado sem autenticação, obteve %d", len(mock.published))
+	}
+}
+
+func TestAccountIDComJWTAceitoComAutenticacaoObrigatoria(t *testing.T) {
+	requireAuthentication(t)
+	useSNSMock(t, &mockSNSClient{})
+
+	req := withJWTClaims(postTransaction(nil), map[string]string{"sub": testAccountID})
+	if resp, _ := handler(context.Background(), req); resp.StatusCode != 200 {
+		t.Errorf("Esperava 200 com claim válida, obteve %d", resp.StatusCode)
+	}
+}
+
+func TestAccountIDMissingOrInvalid(t *testing.T) {
+	for _, accountID := range []string{"", "not-a-uuid"} {
+		body, _ := json.Marshal(map[string]string{"account_id": accountID, "amount": "100", "type": "deposit"})
+		req := postTransaction(nil)
+		req.Body = string(body)
+
+		resp, _ := handler(context.Background(), req)
+		if resp.StatusCode != 400 {
+			t.Errorf("Esperava 400 para account_id %q, obteve %d", accountID, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"go.opentelemetry.io/otel/propagation"
+
+	txevents "finorbit/events"
+	"finorbit/platform/logging"
+)
+
+// ===============================
+// Publicação de eventos
+// ===============================
+//
+// O handler só conhece o Publisher; o broker é escolhido por
+// PUBLISHER_BACKEND: "sns" (padrão), "memory", "file", "kafka" ou "nats".
+
+type Publisher interface {
+	Publish(ctx context.Context, event txevents.TransactionEvent) error
+	Close() error
+}
+
+var publisher Publisher
+
+// newPublisher monta o backend configurado. cfg só é usado pelo SNS.
+func newPublisher(cfg aws.Config) (Publisher, error) {
+	switch backend := os.Getenv("PUBLISHER_BACKEND"); backend {
+	case "", "sns":
+		topicARN := os.Getenv("SNS_TOPIC_ARN")
+		if topicARN == "" {
+			return nil, errors.New("variável SNS_TOPIC_ARN não configurada")
+		}
+		return newSNSPublisher(sns.NewFromConfig(cfg), topicARN), nil
+	case "memory":
+		return newMemoryPublisher(), nil
+	case "file":
+		path := os.Getenv("PUBLISHER_FILE")
+		if path == "" {
+			path = defaultPublisherFile
+		}
+		return newFilePublisher(path)
+	case "kafka":
+		brokers := os.Getenv("KAFKA_BROKERS")
+		topic := os.Getenv("KAFKA_TOPIC")
+		if brokers == "" || topic == "" {
+			return nil, errors.New("KAFKA_BROKERS e KAFKA_TOPIC obrigatórias para PUBLISHER_BACKEND=kafka")
+		}
+		return newKafkaPublisher(strings.Split(brokers, ","), topic), nil
+	case "nats":
+		subject := os.Getenv("NATS_SUBJECT")
+		if subject == "" {
+			subject = defaultNATSSubject
+		}
+		return newNATSPublisher(os.Getenv("NATS_URL"), subject)
+	default:
+		return nil, fmt.Errorf("PUBLISHER_BACKEND desconhecido: %q", backend)
+	}
+}
+
+// ===============================
+// Formato do corpo publicado
+// ===============================
+// EVENT_FORMAT escolhe entre "legacy" (padrão, o JSON do TransactionEvent)
+// e "cloudevents" (CloudEvents 1.0 em modo estruturado, para assinantes
+// externos). O consumer entende os dois.
+type eventFormat struct {
+	ContentType string
+	Encode      func(event txevents.TransactionEvent) ([]byte, error)
+}
+
+var legacyFormat = eventFormat{ContentType: "application/json", Encode: txevents.Encode}
+
+var format = legacyFormat
+
+func newEventFormat() (eventFormat, error) {
+	switch name := os.Getenv("EVENT_FORMAT"); name {
+	case "", "legacy":
+		return legacyFormat, nil
+	case "cloudevents":
+		source := os.Getenv("CLOUDEVENTS_SOURCE")
+		return eventFormat{
+			ContentType: txevents.CloudEventsContentType,
+			Encode: func(event txevents.TransactionEvent) ([]byte, error) {
+				return txevents.EncodeCloudEvent(event, source)
+			},
+		}, nil
+	default:
+		return eventFormat{}, fmt.Errorf("EVENT_FORMAT desconhecido: %q", name)
+	}
+}
+
+// eventAttributes são os metadados enviados junto do payload (atributos no
+// SNS, headers no Kafka/NATS). `type` alimenta o filtro das filas,
+// `schema_version` permite que assinantes filtrem pela versão do contrato e
+// `content-type` indica o formato do corpo. O correlation_id e o contexto de
+// trace (traceparent) do contexto, quando houver, seguem junto para o consumer.
+func eventAttributes(ctx context.Context, event txevents.TransactionEvent) map[string]string {
+	attributes := map[string]string{
+		"type":           event.Type,
+		"event_id":       event.EventID,
+		"schema_version": strconv.Itoa(txevents.SchemaVersion),
+		"content-type":   format.ContentType,
+	}
+	if id := logging.CorrelationIDFromContext(ctx); id != "" {
+		attributes[correlationAttribute] = id
+	}
+	propagator.Inject(ctx, propagation.MapCarrier(attributes))
+	return attributes
+}
+
+// numericAttributes vão ao SNS como Number, para filtros numéricos.
+var numericAttributes = map[string]bool{"schema_version": true}
+
+// ===============================
+// SNS
+// ===============================
+
+// SNSClient é o subconjunto do client SNS usado pelo publisher (mock nos testes).
+type SNSClient interface {
+	Publish(ctx context.Context, input *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+type snsPublisher struct {
+	client   SNSClient
+	topicARN string
+}
+
+func newSNSPublisher(client SNSClient, topicARN string) *snsPublisher {
+	return &snsPublisher{client: client, topicARN: topicARN}
+}
+
+func (p *snsPublisher) Publish(ctx context.Context, event txevents.TransactionEvent) error {
+	data, err := format.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	attributes := map[string]types.MessageAttributeValue{}
+	for name, value := range eventAttributes(ctx, event) {
+		dataType := "String"
+		if numericAttributes[name] {
+			dataType = "Number"
+		}
+		attributes[name] = types.MessageAttributeValue{
+			DataType:    aws.String(dataType),
+			StringValue: aws.String(value),
+		}
+	}
+
+	_, err = p.client.Publish(ctx, &sns.PublishInput{
+		TopicArn:          aws.String(p.topicARN),
+		Message:           aws.String(string(data)),
+		MessageAttributes: attributes,
+	})
+	return err
+}
+
+func (p *snsPublisher) Close() error { return nil }
+
+// ===============================
+// Memória (testes)
+// ===============================
+type memoryPublisher struct {
+	mu     sync.Mutex
+	events []txevents.TransactionEvent
+}
+
+func newMemoryPublisher() *memoryPublisher {
+	return &memoryPublisher{}
+}
+
+func (p *memoryPublisher) Publish(ctx context.Context, event txevents.TransactionEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Events devolve uma cópia dos eventos publicados até agora.
+func (p *memoryPublisher) Events() []txevents.TransactionEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]txevents.TransactionEvent(nil), p.events...)
+}
+
+func (p *memoryPublisher) Close() error { return nil }
+
+// ===============================
+// Arquivo JSONL (desenvolvimento local)
+// ===============================
+const defaultPublisherFile = "events.jsonl"
+
+// filePublisher acrescenta um evento por linha; o arquivo pode ser lido
+// com `tail -f` ou reprocessado por outras ferramentas.
+type filePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newFilePublisher(path string) (*filePublisher, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir %s: %w", path, err)
+	}
+	return &filePublisher{file: f}, nil
+}
+
+func (p *filePublisher) Publish(ctx context.Context, event txevents.TransactionEvent) error {
+	data, err := format.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Uma única escrita por linha para não intercalar eventos
+	_, err = p.file.Write(append(data, '\n'))
+	return err
+}
+
+func (p *filePublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.file.Close()
+}
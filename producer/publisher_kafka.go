@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+
+	txevents "finorbit/events"
+)
+
+// ===============================
+// Kafka
+// ===============================
+
+// kafkaWriter é o subconjunto do *kafka.Writer usado (mock nos testes).
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type kafkaPublisher struct {
+	writer kafkaWriter
+}
+
+func newKafkaPublisher(brokers []string, topic string) *kafkaPublisher {
+	return &kafkaPublisher{writer: &kafka.Writer{
+		Addr:  kafka.TCP(brokers...),
+		Topic: topic,
+		// Mesma conta sempre na mesma partição: preserva a ordem por conta
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+// kafkaMessage usa a conta como chave e leva os atributos nos headers.
+func kafkaMessage(ctx context.Context, event txevents.TransactionEvent) (kafka.Message, error) {
+	data, err := format.Encode(event)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	msg := kafka.Message{Key: []byte(event.UserID), Value: data}
+	for name, value := range eventAttributes(ctx, event) {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: name, Value: []byte(value)})
+	}
+	return msg, nil
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, event txevents.TransactionEvent) error {
+	msg, err := kafkaMessage(ctx, event)
+	if err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(ctx, msg)
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
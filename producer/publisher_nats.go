@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+
+	txevents "finorbit/events"
+)
+
+// ===============================
+// NATS
+// ===============================
+const defaultNATSSubject = "finorbit.transactions"
+
+// natsConn é o subconjunto do *nats.Conn usado (mock nos testes).
+type natsConn interface {
+	PublishMsg(msg *nats.Msg) error
+	FlushWithContext(ctx context.Context) error
+	Drain() error
+}
+
+type natsPublisher struct {
+	conn    natsConn
+	subject string
+}
+
+func newNATSPublisher(url, subject string) (*natsPublisher, error) {
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	conn, err := nats.Connect(url, nats.Name("finorbit-producer"))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao conectar no NATS: %w", err)
+	}
+	return &natsPublisher{conn: conn, subject: subject}, nil
+}
+
+// natsMessage publica em <subject>.<type>, o equivalente ao filtro por
+// `type` das assinaturas SNS → SQS.
+func natsMessage(ctx context.Context, subject string, event txevents.TransactionEvent) (*nats.Msg, error) {
+	data, err := format.Encode(event)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(subject + "." + event.Type)
+	msg.Data = data
+	for name, value := range eventAttributes(ctx, event) {
+		msg.Header.Set(name, value)
+	}
+	return msg, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, event txevents.TransactionEvent) error {
+	msg, err := natsMessage(ctx, p.subject, event)
+	if err != nil {
+		return err
+	}
+	if err := p.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// Confirma a entrega ao servidor antes de responder ao cliente
+	return p.conn.FlushWithContext(ctx)
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
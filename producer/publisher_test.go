@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/shopspring/decimal"
+
+	txevents "finorbit/events"
+)
+
+const (
+	testEventID1 = "3f1a6a2e-1f0b-4b8e-9a61-0c9d2f0b7c11"
+	testEventID2 = "9b2d4c6e-8f01-4a23-b456-789abcdef012"
+)
+
+func testEvent(eventID, eventType string) txevents.TransactionEvent {
+	return txevents.TransactionEvent{
+		EventID:   eventID,
+		UserID:    testAccountID,
+		Amount:    decimal.RequireFromString("10.50"),
+		Type:      eventType,
+		Timestamp: "2025-01-02T03:04:05Z",
+	}
+}
+
+// ------------------------
+// 1️⃣ Seleção do backend
+// ------------------------
+func TestNewPublisher_Backends(t *testing.T) {
+	t.Setenv("PUBLISHER_BACKEND", "memory")
+	pub, err := newPublisher(aws.Config{})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if _, ok := pub.(*memoryPublisher); !ok {
+		t.Errorf("Esperava memoryPublisher, obteve %T", pub)
+	}
+
+	t.Setenv("PUBLISHER_BACKEND", "sns")
+	t.Setenv("SNS_TOPIC_ARN", "arn:aws:sns:us-east-1:123456789012:test-topic")
+	if pub, err = newPublisher(aws.Config{}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if _, ok := pub.(*snsPublisher); !ok {
+		t.Errorf("Esperava snsPublisher, obteve %T", pub)
+	}
+}
+
+func TestNewPublisher_ConfiguracaoIncompleta(t *testing.T) {
+	cases := map[string]map[string]string{
+		"sns sem tópico":   {"PUBLISHER_BACKEND": "", "SNS_TOPIC_ARN": ""},
+		"kafka sem broker": {"PUBLISHER_BACKEND": "kafka", "KAFKA_BROKERS": "", "KAFKA_TOPIC": "tx"},
+		"desconhecido":     {"PUBLISHER_BACKEND": "rabbitmq"},
+	}
+	for name, env := range cases {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range env {
+				t.Setenv(k, v)
+			}
+			if _, err := newPublisher(aws.Config{}); err == nil {
+				t.Error("Esperava erro de configuração")
+			}
+		})
+	}
+}
+
+// ------------------------
+// 2️⃣ Backends
+// ------------------------
+func TestSNSPublisher_Atributos(t *testing.T) {
+	mock := &mockSNSClient{}
+	pub := newSNSPublisher(mock, "arn:topic")
+
+	if err := pub.Publish(context.Background(), testEvent(testEventID1, "withdraw")); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	input := mock.published[0]
+	if *input.TopicArn != "arn:topic" {
+		t.Errorf("Tópico incorreto: %s", *input.TopicArn)
+	}
+	if got := *input.MessageAttributes["type"].StringValue; got != "withdraw" {
+		t.Errorf("Esperava atributo type=withdraw, obteve %s", got)
+	}
+	version := input.MessageAttributes["schema_version"]
+	if *version.DataType != "Number" || *version.StringValue != "1" {
+		t.Errorf("Esperava schema_version Number=1, obteve %s=%s", *version.DataType, *version.StringValue)
+	}
+	if got := publishedEvent(t, input).EventID; got != testEventID1 {
+		t.Errorf("Esperava event_id %s, obteve %s", testEventID1, got)
+	}
+}
+
+func TestFilePublisher_AcrescentaLinhas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	pub, err := newFilePublisher(path)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	for _, id := range []string{testEventID1, testEventID2} {
+		if err := pub.Publish(context.Background(), testEvent(id, "deposit")); err != nil {
+			t.Fatalf("Erro ao publicar: %v", err)
+		}
+	}
+	if err := pub.Close(); err != nil {
+		t.Fatalf("Erro ao fechar: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Erro ao abrir arquivo: %v", err)
+	}
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event txevents.TransactionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Linha não é um TransactionEvent: %v", err)
+		}
+		ids = append(ids, event.EventID)
+	}
+	if len(ids) != 2 || ids[0] != testEventID1 || ids[1] != testEventID2 {
+		t.Errorf("Esperava [%s %s], obteve %v", testEventID1, testEventID2, ids)
+	}
+}
+
+func TestKafkaMessage_ChaveEHeaders(t *testing.T) {
+	msg, err := kafkaMessage(context.Background(), testEvent(testEventID1, "deposit"))
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if string(msg.Key) != testAccountID {
+		t.Errorf("Esperava a conta como chave, obteve %s", msg.Key)
+	}
+
+	headers := map[string]string{}
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	if headers["type"] != "deposit" || headers["event_id"] != testEventID1 {
+		t.Errorf("Headers incorretos: %v", headers)
+	}
+}
+
+func TestNATSMessage_SubjectPorTipo(t *testing.T) {
+	msg, err := natsMessage(context.Background(), defaultNATSSubject, testEvent(testEventID1, "withdraw"))
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if msg.Subject != "finorbit.transactions.withdraw" {
+		t.Errorf("Subject incorreto: %s", msg.Subject)
+	}
+	if msg.Header.Get("event_id") != testEventID1 {
+		t.Errorf("Header event_id ausente: %v", msg.Header)
+	}
+}
+
+// ------------------------
+// 3️⃣ Formato CloudEvents
+// ------------------------
+func useEventFormat(t *testing.T, name string) {
+	t.Helper()
+	t.Setenv("EVENT_FORMAT", name)
+	f, err := newEventFormat()
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	format = f
+	t.Cleanup(func() { format = legacyFormat })
+}
+
+func TestSNSPublisher_CloudEvents(t *testing.T) {
+	useEventFormat(t, "cloudevents")
+	mock := &mockSNSClient{}
+	pub := newSNSPublisher(mock, "arn:topic")
+
+	if err := pub.Publish(context.Background(), testEvent(testEventID1, "deposit")); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	input := mock.published[0]
+	ce, err := txevents.DecodeCloudEvent([]byte(*input.Message))
+	if err != nil {
+		t.Fatalf("Mensagem não é um CloudEvent: %v", err)
+	}
+	if ce.ID != testEventID1 || ce.Type != "finorbit.transaction.deposit" || ce.Source != txevents.DefaultCloudEventSource {
+		t.Errorf("Atributos CloudEvents incorretos: %+v", ce)
+	}
+	if got := *input.MessageAttributes["content-type"].StringValue; got != txevents.CloudEventsContentType {
+		t.Errorf("Esperava content-type %s, obteve %s", txevents.CloudEventsContentType, got)
+	}
+	// O filtro das filas continua funcionando pelo atributo type
+	if got := *input.MessageAttributes["type"].StringValue; got != "deposit" {
+		t.Errorf("Esperava atributo type=deposit, obteve %s", got)
+	}
+}
+
+func TestNewEventFormat_Desconhecido(t *testing.T) {
+	t.Setenv("EVENT_FORMAT", "avro")
+	if _, err := newEventFormat(); err == nil {
+		t.Error("Esperava erro para EVENT_FORMAT desconhecido")
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pborman/uuid"
+)
+
+// ===============================
+// Modo servidor HTTP (fora da Lambda)
+// ===============================
+//
+// PRODUCER_MODE=server expõe as mesmas rotas do API Gateway via net/http.
+// Cada requisição é convertida em APIGatewayV2HTTPRequest e passa pelo
+// mesmo handler da Lambda, então o comportamento é idêntico.
+
+const (
+	defaultHTTPAddr = ":8080"
+	maxBodyBytes    = 1 << 20
+	shutdownTimeout = 10 * time.Second
+
+	// serverRelayInterval segue o agendamento do relay na AWS (rate(1 minute))
+	serverRelayInterval = time.Minute
+)
+
+type lambdaHTTPHandler func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error)
+
+// routes registra as rotas publicadas no API Gateway.
+func routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/transaction", lambdaAdapter("POST /transaction", handler))
+	return mux
+}
+
+// lambdaAdapter traduz net/http ⇄ eventos do API Gateway HTTP API.
+func lambdaAdapter(routeKey string, h lambdaHTTPHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		req, err := toAPIGatewayRequest(r, routeKey)
+		if err != nil {
+			// Só o limite de tamanho é 413; conexão cortada no meio do corpo
+			// e afins são erro do cliente
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "corpo da requisição excede o limite", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "corpo da requisição inválido", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h(r.Context(), req)
+		if err != nil {
+			// Na Lambda um erro vira 500 do API Gateway; aqui fazemos o mesmo
+			slog.ErrorContext(r.Context(), "Erro no handler", logKeyError, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		writeAPIGatewayResponse(w, resp)
+	})
+}
+
+func toAPIGatewayRequest(r *http.Request, routeKey string) (events.APIGatewayV2HTTPRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayV2HTTPRequest{}, fmt.Errorf("erro ao ler corpo: %w", err)
+	}
+
+	// O API Gateway entrega os headers em minúsculas, com valores repetidos
+	// unidos por vírgula
+	headers := make(map[string]string, len(r.Header))
+	for name, values := range r.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	var query map[string]string
+	if values := r.URL.Query(); len(values) > 0 {
+		query = make(map[string]string, len(values))
+		for name, v := range values {
+			query[name] = strings.Join(v, ",")
+		}
+	}
+
+	sourceIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		sourceIP = r.RemoteAddr
+	}
+
+	return events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              routeKey,
+		RawPath:               r.URL.Path,
+		RawQueryString:        r.URL.RawQuery,
+		Headers:               headers,
+		QueryStringParameters: query,
+		Body:                  string(body),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:  routeKey,
+			RequestID: uuid.NewRandom().String(),
+			TimeEpoch: time.Now().UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  sourceIP,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}, nil
+}
+
+func writeAPIGatewayResponse(w http.ResponseWriter, resp events.APIGatewayV2HTTPResponse) {
+	for name, value := range resp.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range resp.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	for _, cookie := range resp.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			slog.Error("Corpo base64 inválido na resposta", logKeyError, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		body = decoded
+	}
+
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// runRelayLoop faz no servidor o papel da Lambda outbox-relay: republica
+// as entradas pendentes a cada intervalo até ctx ser cancelado.
+func runRelayLoop(ctx context.Context, store OutboxStore, pub Publisher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := relayOutbox(ctx, store, pub, defaultRelayBatchSize); err != nil {
+				slog.ErrorContext(ctx, "Erro no relay do outbox", logKeyError, err)
+			}
+		}
+	}
+}
+
+// runServer atende até receber SIGINT/SIGTERM e então encerra
+// graciosamente, aguardando as requisições em andamento. Com outbox
+// configurado, o relay roda no mesmo processo.
+func runServer(addr string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	relayDone := make(chan struct{})
+	if outbox != nil && publisher != nil {
+		go func() {
+			defer close(relayDone)
+			runRelayLoop(ctx, outbox, publisher, serverRelayInterval)
+		}()
+	} else {
+		close(relayDone)
+	}
+	// O publisher é fechado por quem chamou; o relay precisa ter parado
+	// antes. stop cancela ctx em qualquer saída, inclusive falha ao ouvir.
+	defer func() {
+		stop()
+		<-relayDone
+	}()
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("Producer ouvindo", "addr", addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("servidor HTTP encerrado: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Encerrando servidor HTTP")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("erro ao encerrar servidor HTTP: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
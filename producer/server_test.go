@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	txevents "finorbit/events"
+)
+
+// ------------------------
+// 1️⃣ Tradução net/http → API Gateway
+// ------------------------
+func TestToAPIGatewayRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/transaction?debug=1&debug=2", strings.NewReader(`{"a":1}`))
+	r.Header.Set("Idempotency-Key", "pedido-1")
+	r.Header.Add("Accept-Language", "en")
+
+	req, err := toAPIGatewayRequest(r, "POST /transaction")
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	if req.RequestContext.HTTP.Method != http.MethodPost || req.RawPath != "/transaction" {
+		t.Errorf("Método/caminho incorretos: %s %s", req.RequestContext.HTTP.Method, req.RawPath)
+	}
+	if req.Headers["idempotency-key"] != "pedido-1" {
+		t.Errorf("Esperava headers em minúsculas, obteve %v", req.Headers)
+	}
+	if req.QueryStringParameters["debug"] != "1,2" {
+		t.Errorf("Esperava query com valores unidos por vírgula, obteve %v", req.QueryStringParameters)
+	}
+	if req.Body != `{"a":1}` || req.RequestContext.RequestID == "" {
+		t.Errorf("Corpo ou RequestID ausentes: %+v", req)
+	}
+}
+
+// ------------------------
+// 2️⃣ Mesmo handler da Lambda
+// ------------------------
+func TestServer_PublicaTransacao(t *testing.T) {
+	pub := newMemoryPublisher()
+	publisher = pub
+	t.Cleanup(func() { publisher = nil })
+
+	srv := httptest.NewServer(routes())
+	defer srv.Close()
+
+	body := `{"account_id":"` + testAccountID + `","amount":"10","type":"deposit"}`
+	resp, err := http.Post(srv.URL+"/transaction", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Erro na requisição: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+	if got := len(pub.Events()); got != 1 {
+		t.Errorf("Esperava 1 evento publicado, obteve %d", got)
+	}
+}
+
+func TestServer_ProblemJSON(t *testing.T) {
+	srv := httptest.NewServer(routes())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/transaction")
+	if err != nil {
+		t.Fatalf("Erro na requisição: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Esperava 405, obteve %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != problemContentType {
+		t.Errorf("Esperava %s, obteve %s", problemContentType, ct)
+	}
+
+	var problem Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatalf("Corpo não é problem+json: %v", err)
+	}
+	if problem.Code != codeMethodNotAllowed || problem.Instance == "" {
+		t.Errorf("Problem inesperado: %+v", problem)
+	}
+}
+
+func TestWriteAPIGatewayResponse_Base64(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeAPIGatewayResponse(rec, events.APIGatewayV2HTTPResponse{
+		StatusCode:      http.StatusCreated,
+		Headers:         map[string]string{"X-Teste": "1"},
+		Body:            "b2zDoQ==",
+		IsBase64Encoded: true,
+	})
+
+	if rec.Code != http.StatusCreated || rec.Header().Get("X-Teste") != "1" || rec.Body.String() != "olá" {
+		t.Errorf("Resposta traduzida incorretamente: %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
+
+func TestLambdaAdapter_ErroDoHandler(t *testing.T) {
+	failing := func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return events.APIGatewayV2HTTPResponse{}, context.DeadlineExceeded
+	}
+
+	rec := httptest.NewRecorder()
+	lambdaAdapter("POST /transaction", failing).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/transaction", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Esperava 500, obteve %d", rec.Code)
+	}
+}
+
+func TestLambdaAdapter_CorpoGrandeDemais(t *testing.T) {
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(strings.Repeat("a", maxBodyBytes+1))
+	lambdaAdapter("POST /transaction", handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/transaction", body))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Esperava 413, obteve %d", rec.Code)
+	}
+}
+
+func TestLambdaAdapter_FalhaNaLeituraEh400(t *testing.T) {
+	rec := httptest.NewRecorder()
+	body := iotest.ErrReader(errors.New("conexão encerrada"))
+	lambdaAdapter("POST /transaction", handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/transaction", body))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Esperava 400, obteve %d", rec.Code)
+	}
+}
+
+// ------------------------
+// 3️⃣ Relay no modo servidor
+// ------------------------
+func TestRunRelayLoop_RepublicaPendentes(t *testing.T) {
+	store := newMemoryOutbox()
+	now := time.Now().UTC()
+	payload, _ := txevents.Encode(testEvent(testEventID1, "deposit"))
+	_ = store.Save(context.Background(), OutboxEntry{EventID: testEventID1, Payload: string(payload), CreatedAt: now, NextAttemptAt: now})
+	pub := newMemoryPublisher()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runRelayLoop(ctx, store, pub, time.Millisecond)
+	}()
+
+	deadline := time.After(time.Second)
+	for len(pub.Events()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("Relay não republicou a entrada pendente")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	if got := store.entries[testEventID1].Status; got != outboxSent {
+		t.Errorf("Esperava status %q, obteve %q", outboxSent, got)
+	}
+}
+
+func TestRunServer_EnderecoOcupadoEncerraRelay(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Erro ao reservar porta: %v", err)
+	}
+	defer busy.Close()
+
+	useOutbox(t)
+	publisher = newMemoryPublisher()
+	t.Cleanup(func() { publisher = nil })
+
+	done := make(chan error, 1)
+	go func() { done <- runServer(busy.Addr().String()) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Esperava erro com o endereço ocupado")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runServer travou esperando o relay")
+	}
+}
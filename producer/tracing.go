@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"finorbit/platform/tracing"
+)
+
+// ===============================
+// Tracing (OpenTelemetry)
+// ===============================
+//
+// OTEL_TRACES_EXPORTER escolhe o exportador: "otlp" (OTLP/HTTP, configurado
+// pelas variáveis OTEL_EXPORTER_OTLP_*), "stdout" (execução local) ou
+// vazio/"none" para desligar. Com o tracing ligado, o contexto W3C
+// (traceparent) segue nos atributos da mensagem e o consumer continua o trace.
+const (
+	tracerName         = "finorbit/producer"
+	defaultServiceName = "finorbit-producer"
+)
+
+// Com o tracing desligado os spans são no-op e nada é propagado.
+var tracerProvider, propagator = tracing.Disabled()
+
+func tracer() trace.Tracer {
+	return tracerProvider.Tracer(tracerName)
+}
+
+// setupTracing liga o exportador de OTEL_TRACES_EXPORTER (ver
+// finorbit/platform/tracing).
+func setupTracing(ctx context.Context) error {
+	provider, prop, err := tracing.Setup(ctx, defaultServiceName)
+	if err != nil {
+		return err
+	}
+	tracerProvider, propagator = provider, prop
+	return nil
+}
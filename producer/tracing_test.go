@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useSpanRecorder liga o tracing com um gravador em memória durante o teste.
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := tracerProvider, propagator
+	tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	propagator = propagation.TraceContext{}
+	t.Cleanup(func() { tracerProvider, propagator = prevProvider, prevPropagator })
+	return recorder
+}
+
+func endedSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, s := range recorder.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	t.Fatalf("Span %q não encontrado", name)
+	return nil
+}
+
+// ------------------------
+// 1️⃣ Spans do handler e da publicação
+// ------------------------
+func TestTracing_HandlerInjetaTraceparent(t *testing.T) {
+	recorder := useSpanRecorder(t)
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	resp, _ := handler(context.Background(), postTransaction(nil))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+
+	server := endedSpan(t, recorder, "POST /transaction")
+	publish := endedSpan(t, recorder, "transactions publish")
+	if publish.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("Span de publicação deveria ser filho do span do handler")
+	}
+	if publish.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("Esperava span do tipo producer, obteve %v", publish.SpanKind())
+	}
+
+	// O consumer continua o trace a partir do traceparent do atributo
+	attr, ok := mock.published[0].MessageAttributes["traceparent"]
+	if !ok {
+		t.Fatal("Atributo traceparent ausente na mensagem")
+	}
+	carrier := propagation.MapCarrier{"traceparent": *attr.StringValue}
+	remote := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+	if remote.SpanID() != publish.SpanContext().SpanID() || remote.TraceID() != server.SpanContext().TraceID() {
+		t.Errorf("traceparent não aponta para o span de publicação: %s", *attr.StringValue)
+	}
+}
+
+func TestTracing_ContinuaTraceDoCliente(t *testing.T) {
+	recorder := useSpanRecorder(t)
+	useSNSMock(t, &mockSNSClient{})
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	handler(context.Background(), postTransaction(map[string]string{"traceparent": traceparent}))
+
+	server := endedSpan(t, recorder, "POST /transaction")
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Esperava continuar o trace do cliente, obteve %s", got)
+	}
+}
+
+func TestTracing_FalhaNaPublicacaoMarcaErro(t *testing.T) {
+	recorder := useSpanRecorder(t)
+	useSNSMock(t, &mockSNSClient{shouldFail: true})
+
+	handler(context.Background(), postTransaction(nil))
+
+	if got := endedSpan(t, recorder, "transactions publish").Status().Code.String(); got != "Error" {
+		t.Errorf("Esperava status Error no span de publicação, obteve %s", got)
+	}
+	if got := endedSpan(t, recorder, "POST /transaction").Status().Code.String(); got != "Error" {
+		t.Errorf("Esperava status Error no span do handler, obteve %s", got)
+	}
+}
+
+func TestTracing_DesligadoNaoInjetaTraceparent(t *testing.T) {
+	mock := &mockSNSClient{}
+	useSNSMock(t, mock)
+
+	handler(context.Background(), postTransaction(nil))
+	if _, ok := mock.published[0].MessageAttributes["traceparent"]; ok {
+		t.Error("Sem tracing configurado, traceparent não deveria ser enviado")
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/shopspring/decimal"
+
+	"finorbit/platform/logging"
+)
+
+// =========================================================
+// 💰 Saldo e extrato de contas
+// =========================================================
+const (
+	defaultStatementLimit = 50
+	maxStatementLimit     = 200
+)
+
+type AccountBalance struct {
+	AccountID string          `json:"account_id"`
+	Balance   decimal.Decimal `json:"balance"`
+	UpdatedAt string          `json:"updated_at"`
+}
+
+type StatementLine struct {
+	EventID   string          `json:"event_id,omitempty"`
+	Type      string          `json:"type"`
+	Amount    decimal.Decimal `json:"amount"`
+	Balance   decimal.Decimal `json:"balance"`
+	Timestamp string          `json:"timestamp"`
+}
+
+type Statement struct {
+	AccountID  string          `json:"account_id"`
+	Lines      []StatementLine `json:"lines"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type statementQuery struct {
+	from, to *time.Time
+	after    int64
+	limit    int
+}
+
+// =========================================================
+// 🧾 Parâmetros do extrato
+// =========================================================
+func encodeCursor(postingID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(postingID, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("cursor inválido")
+	}
+	return id, nil
+}
+
+// parseDateParam aceita RFC3339 ou apenas a data (YYYY-MM-DD, em UTC).
+func parseDateParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func parseStatementQuery(params map[string]string) (statementQuery, error) {
+	q := statementQuery{limit: defaultStatementLimit}
+
+	var err error
+	if q.from, err = parseDateParam(params["from"]); err != nil {
+		return q, fmt.Errorf("parâmetro from inválido")
+	}
+	if q.to, err = parseDateParam(params["to"]); err != nil {
+		return q, fmt.Errorf("parâmetro to inválido")
+	}
+	if q.from != nil && q.to != nil && !q.from.Before(*q.to) {
+		return q, fmt.Errorf("from deve ser anterior a to")
+	}
+
+	if cursor := params["cursor"]; cursor != "" {
+		if q.after, err = decodeCursor(cursor); err != nil {
+			return q, fmt.Errorf("cursor inválido")
+		}
+	}
+
+	if limit := params["limit"]; limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > maxStatementLimit {
+			return q, fmt.Errorf("limit deve estar entre 1 e %d", maxStatementLimit)
+		}
+		q.limit = n
+	}
+
+	return q, nil
+}
+
+// =========================================================
+// 🔎 Consultas
+// =========================================================
+func findAccountBalance(ctx context.Context, d *sql.DB, accountID string) (*AccountBalance, error) {
+	var (
+		raw       string
+		updatedAt time.Time
+	)
+	err := d.QueryRowContext(ctx,
+		`SELECT balance::text, updated_at FROM accounts WHERE id = $1 AND kind = 'user'`,
+		accountID,
+	).Scan(&raw, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := decimal.NewFromString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("saldo inválido no banco: %w", err)
+	}
+	return &AccountBalance{
+		AccountID: accountID,
+		Balance:   balance,
+		UpdatedAt: updatedAt.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// findStatement lê as postings da conta em ordem de lançamento. O saldo
+// após cada linha (balance_after) é gravado pelo consumer junto com a
+// posting, então cada página mostra o saldo real sem somar o histórico.
+// Como no saldo, só contas de usuário têm extrato: a conta de sistema não
+// materializa saldo e suas postings têm balance_after nulo.
+func findStatement(ctx context.Context, d *sql.DB, accountID string, q statementQuery) (*Statement, error) {
+	rows, err := d.QueryContext(ctx,
+		`SELECT p.id, t.event_id, t.type, p.amount::text, p.balance_after::text, t.timestamp
+		 FROM postings p
+		 JOIN journal_entries j ON j.id = p.journal_entry_id
+		 JOIN transactions t ON t.id = j.transaction_id
+		 WHERE p.account_id = $1
+		   AND p.balance_after IS NOT NULL
+		   AND ($2::timestamp IS NULL OR t.timestamp >= $2)
+		   AND ($3::timestamp IS NULL OR t.timestamp < $3)
+		   AND p.id > $4
+		 ORDER BY p.id
+		 LIMIT $5`,
+		accountID, nullableTime(q.from), nullableTime(q.to), q.after, q.limit+1,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statement := &Statement{AccountID: accountID, Lines: []StatementLine{}}
+	var lastID int64
+	for rows.Next() {
+		var (
+			postingID             int64
+			eventID               sql.NullString
+			line                  StatementLine
+			rawAmount, rawBalance string
+			timestamp             time.Time
+		)
+		if err := rows.Scan(&postingID, &eventID, &line.Type, &rawAmount, &rawBalance, &timestamp); err != nil {
+			return nil, err
+		}
+
+		// Busca uma linha a mais só para saber se existe próxima página
+		if len(statement.Lines) == q.limit {
+			statement.NextCursor = encodeCursor(lastID)
+			break
+		}
+
+		if line.Amount, err = decimal.NewFromString(rawAmount); err != nil {
+			return nil, fmt.Errorf("valor inválido no banco: %w", err)
+		}
+		if line.Balance, err = decimal.NewFromString(rawBalance); err != nil {
+			return nil, fmt.Errorf("saldo inválido no banco: %w", err)
+		}
+		line.EventID = eventID.String
+		line.Timestamp = timestamp.UTC().Format(time.RFC3339)
+		statement.Lines = append(statement.Lines, line)
+		lastID = postingID
+	}
+	return statement, rows.Err()
+}
+
+func nullableTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// =========================================================
+// 🌐 GET /accounts/{id}/balance e /accounts/{id}/statement
+// =========================================================
+func getAccountBalance(ctx context.Context, d *sql.DB, req events.APIGatewayV2HTTPRequest) events.APIGatewayV2HTTPResponse {
+	accountID := strings.ToLower(req.PathParameters["id"])
+	if !uuidPattern.MatchString(accountID) {
+		return errorResponse(http.StatusBadRequest, "ID de conta inválido")
+	}
+	// Conta de outro usuário responde como inexistente, como em
+	// /transactions/{id}, para não revelar quais contas existem
+	if !canAccessAccount(req, accountID) {
+		return errorResponse(http.StatusNotFound, "Conta não encontrada")
+	}
+
+	balance, err := findAccountBalance(ctx, d, accountID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Erro ao consultar saldo", "account_id", accountID, logging.KeyError, err)
+		return errorResponse(http.StatusInternalServerError, "Erro ao consultar saldo")
+	}
+	if balance == nil {
+		return errorResponse(http.StatusNotFound, "Conta não encontrada")
+	}
+
+	return jsonResponse(http.StatusOK, balance)
+}
+
+func getAccountStatement(ctx context.Context, d *sql.DB, req events.APIGatewayV2HTTPRequest) events.APIGatewayV2HTTPResponse {
+	accountID := strings.ToLower(req.PathParameters["id"])
+	if !uuidPattern.MatchString(accountID) {
+		return errorResponse(http.StatusBadRequest, "ID de conta inválido")
+	}
+	if !canAccessAccount(req, accountID) {
+		return errorResponse(http.StatusNotFound, "Conta não encontrada")
+	}
+
+	q, err := parseStatementQuery(req.QueryStringParameters)
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, err.Error())
+	}
+
+	statement, err := findStatement(ctx, d, accountID, q)
+	if err != nil {
+		slog.ErrorContext(ctx, "Erro ao consultar extrato", "account_id", accountID, logging.KeyError, err)
+		return errorResponse(http.StatusInternalServerError, "Erro ao consultar extrato")
+	}
+
+	return jsonResponse(http.StatusOK, statement)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+
+	"finorbit/platform/logging"
+)
+
+const testAccountID = "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b"
+
+func accountRequest(route string, params map[string]string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		RouteKey:              route,
+		PathParameters:        map[string]string{"id": testAccountID},
+		QueryStringParameters: params,
+	}
+}
+
+// =========================================================
+// 💰 GET /accounts/{id}/balance
+// =========================================================
+func TestAccountBalance(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM accounts WHERE id`).WithArgs(testAccountID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "updated_at"}).AddRow("1234.56", time.Now()))
+
+	resp, _ := handler(context.Background(), accountRequest("GET /accounts/{id}/balance", nil))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+
+	var balance AccountBalance
+	json.Unmarshal([]byte(resp.Body), &balance)
+	if balance.Balance.String() != "1234.56" {
+		t.Errorf("Esperava saldo 1234.56, obteve %s", balance.Balance)
+	}
+}
+
+func TestAccountBalance_NotFound(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM accounts WHERE id`).WillReturnRows(sqlmock.NewRows([]string{"balance", "updated_at"}))
+
+	resp, _ := handler(context.Background(), accountRequest("GET /accounts/{id}/balance", nil))
+	if resp.StatusCode != 404 {
+		t.Errorf("Esperava 404, obteve %d", resp.StatusCode)
+	}
+}
+
+func TestAccounts_OutraContaNaoEncontrada(t *testing.T) {
+	for _, route := range []string{"GET /accounts/{id}/balance", "GET /accounts/{id}/statement"} {
+		resetDBSingleton()
+		dbMock, _, _ := sqlmock.New()
+		db = dbMock
+
+		req := withClaim(accountRequest(route, nil), "0a0b0c0d-0000-4000-8000-000000000000")
+
+		resp, _ := handler(context.Background(), req)
+		if resp.StatusCode != 404 {
+			t.Errorf("%s: esperava 404 para conta de outro usuário, obteve %d", route, resp.StatusCode)
+		}
+		dbMock.Close()
+	}
+}
+
+func TestAccountBalance_ErroLogaContaMascarada(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	mock.ExpectQuery(`FROM accounts WHERE id`).WillReturnError(errors.New("conexão perdida"))
+
+	resp, _ := handler(context.Background(), accountRequest("GET /accounts/{id}/balance", nil))
+	if resp.StatusCode != 500 {
+		t.Fatalf("Esperava 500, obteve %d", resp.StatusCode)
+	}
+	if strings.Contains(buf.String(), testAccountID) {
+		t.Errorf("Conta apareceu sem máscara no log: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"account_id":"`+logging.MaskAccountID(testAccountID)+`"`) {
+		t.Errorf("Esperava account_id mascarado no log: %s", buf.String())
+	}
+}
+
+// =========================================================
+// 🧾 GET /accounts/{id}/statement
+// =========================================================
+func statementRows() *sqlmock.Rows {
+	ts := time.Date(2025, 11, 7, 12, 0, 0, 0, time.UTC)
+	return sqlmock.NewRows([]string{"posting_id", "event_id", "type", "amount", "balance_after", "timestamp"}).
+		AddRow(10, "e1", "deposit", "100.00", "100.00", ts).
+		AddRow(11, "e2", "withdraw", "-30.10", "69.90", ts).
+		AddRow(15, "e3", "deposit", "0.10", "70.00", ts)
+}
+
+func TestAccountStatement_Paginacao(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM postings p`).
+		WithArgs(testAccountID, nil, nil, int64(0), 3).
+		WillReturnRows(statementRows())
+
+	resp, _ := handler(context.Background(), accountRequest("GET /accounts/{id}/statement", map[string]string{"limit": "2"}))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	var statement Statement
+	json.Unmarshal([]byte(resp.Body), &statement)
+	if len(statement.Lines) != 2 {
+		t.Fatalf("Esperava 2 linhas, obteve %d", len(statement.Lines))
+	}
+	if statement.Lines[1].Amount.String() != "-30.1" || statement.Lines[1].Balance.String() != "69.9" {
+		t.Errorf("Linha com valor/saldo inesperado: %+v", statement.Lines[1])
+	}
+
+	after, err := decodeCursor(statement.NextCursor)
+	if err != nil || after != 11 {
+		t.Errorf("Esperava cursor apontando para a posting 11, obteve %d (%v)", after, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestAccountStatement_UltimaPaginaSemCursor(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	from := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM postings p`).
+		WithArgs(testAccountID, from, nil, int64(11), defaultStatementLimit+1).
+		WillReturnRows(statementRows())
+
+	params := map[string]string{"from": "2025-11-01", "cursor": encodeCursor(11)}
+	resp, _ := handler(context.Background(), accountRequest("GET /accounts/{id}/statement", params))
+
+	var statement Statement
+	json.Unmarshal([]byte(resp.Body), &statement)
+	if len(statement.Lines) != 3 || statement.NextCursor != "" {
+		t.Errorf("Esperava 3 linhas sem próxima página, obteve %d e cursor %q", len(statement.Lines), statement.NextCursor)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectativas não atendidas: %v", err)
+	}
+}
+
+func TestParseStatementQuery_Invalidos(t *testing.T) {
+	cases := []map[string]string{
+		{"from": "ontem"},
+		{"to": "2025-13-01"},
+		{"from": "2025-11-02", "to": "2025-11-01"},
+		{"cursor": "###"},
+		{"limit": "0"},
+		{"limit": "1000"},
+	}
+
+	for _, params := range cases {
+		if _, err := parseStatementQuery(params); err == nil {
+			t.Errorf("Esperava erro para %v", params)
+		}
+	}
+}
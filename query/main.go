@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/shopspring/decimal"
+
+	"finorbit/platform/dbcreds"
+	"finorbit/platform/logging"
+)
+
+// =========================================================
+// 💡 Status de uma transação
+// =========================================================
+// Ciclo de vida: "accepted" enquanto o evento está só no outbox do
+// producer; a partir daí o consumer grava "persisted", "rejected" ou
+// "failed" no banco.
+const (
+	statusAccepted  = "accepted"
+	statusPersisted = "persisted"
+	statusRejected  = "rejected"
+	statusFailed    = "failed"
+)
+
+type TransactionStatus struct {
+	EventID   string           `json:"event_id"`
+	Status    string           `json:"status"`
+	AccountID string           `json:"account_id,omitempty"`
+	Type      string           `json:"type,omitempty"`
+	Amount    *decimal.Decimal `json:"amount,omitempty"`
+	Timestamp string           `json:"timestamp,omitempty"`
+	Reason    string           `json:"reason,omitempty"`
+	Attempts  int              `json:"attempts,omitempty"`
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// =========================================================
+// 🔒 Conexão com o banco (somente leitura), compartilhada pelo container
+// =========================================================
+// Mesmo esquema do consumer: a conexão é aberta na primeira invocação que
+// precisar dela e, se falhar, a próxima tenta de novo.
+var (
+	db   *sql.DB
+	dbMu sync.Mutex
+
+	// connectDB abre e valida a conexão; os testes trocam por uma versão
+	// que simula falhas.
+	connectDB = openDB
+
+	// dbCredentials vem de DB_CREDENTIALS_SOURCE (ver finorbit/platform/dbcreds).
+	dbCredentials dbcreds.Provider = dbcreds.Env{}
+)
+
+// errDBDisabled indica GO_ENV=test: não há banco e não adianta insistir.
+var errDBDisabled = errors.New("conexão com o banco desativada em GO_ENV=test")
+
+const (
+	dbConnectAttempts = 5
+	dbConnectMaxDelay = 5 * time.Second
+	// dbDeadlineMargin é o tempo reservado para responder 503 antes de a
+	// invocação estourar o prazo.
+	dbDeadlineMargin = time.Second
+)
+
+// dbConnectBaseDelay é a espera antes da 2ª tentativa; dobra a cada nova falha.
+var dbConnectBaseDelay = 200 * time.Millisecond
+
+func dbConnectBackoff(attempt int) time.Duration {
+	delay := dbConnectBaseDelay << (attempt - 1)
+	if delay > dbConnectMaxDelay || delay <= 0 {
+		return dbConnectMaxDelay
+	}
+	return delay
+}
+
+func openDB(ctx context.Context) (*sql.DB, error) {
+	if os.Getenv("GO_ENV") == "test" {
+		return nil, errDBDisabled
+	}
+
+	// DB_SSLMODE permite desligar o TLS num Postgres local (padrão: require)
+	sslMode := os.Getenv("DB_SSLMODE")
+	if sslMode == "" {
+		sslMode = "require"
+	}
+
+	// Cada conexão nova do pool busca a credencial atual, então a
+	// rotação da senha não derruba a query
+	conn := sql.OpenDB(dbcreds.NewConnector(dbCredentials, sslMode))
+
+	// sql.OpenDB não conecta; o ping garante que o banco responde
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("falha ao conectar ao banco: %w", err)
+	}
+	return conn, nil
+}
+
+// getDB devolve a conexão do container, abrindo-a se preciso. As
+// tentativas respeitam o prazo de ctx (o da invocação).
+func getDB(ctx context.Context) (*sql.DB, error) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	if db != nil {
+		return db, nil
+	}
+
+	conn, err := connectWithRetry(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Conexão com RDS estabelecida")
+	db = conn
+	return db, nil
+}
+
+func connectWithRetry(ctx context.Context) (*sql.DB, error) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		var conn *sql.DB
+		conn, err = connectDB(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		if errors.Is(err, errDBDisabled) {
+			return nil, err
+		}
+		if attempt == dbConnectAttempts {
+			return nil, fmt.Errorf("banco indisponível após %d tentativas: %w", attempt, err)
+		}
+
+		delay := dbConnectBackoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline)-delay < dbDeadlineMargin {
+			return nil, fmt.Errorf("banco indisponível após %d tentativas, sem prazo para outra: %w", attempt, err)
+		}
+		slog.WarnContext(ctx, "Falha ao conectar ao banco, nova tentativa",
+			"attempt", attempt, "retry_in_ms", delay.Milliseconds(), logging.KeyError, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("banco indisponível após %d tentativas: %w", attempt, errors.Join(err, ctx.Err()))
+		case <-time.After(delay):
+		}
+	}
+}
+
+// =========================================================
+// 🔐 Autorização
+// =========================================================
+// defaultAccountIDClaim segue a mesma convenção do producer.
+const defaultAccountIDClaim = "sub"
+
+// authenticatedAccountID lê o ID da conta das claims do JWT validado pelo
+// authorizer do API Gateway. Retorna vazio em rotas sem autenticação.
+func authenticatedAccountID(req events.APIGatewayV2HTTPRequest) string {
+	auth := req.RequestContext.Authorizer
+	if auth == nil || auth.JWT == nil {
+		return ""
+	}
+
+	claim := os.Getenv("ACCOUNT_ID_CLAIM")
+	if claim == "" {
+		claim = defaultAccountIDClaim
+	}
+	return auth.JWT.Claims[claim]
+}
+
+// allowUnauthenticated libera as consultas sem JWT. Só para desenvolvimento
+// local (ALLOW_UNAUTHENTICATED=true): sem a claim, qualquer cliente leria
+// saldo e extrato de qualquer conta.
+var allowUnauthenticated bool
+
+// canAccessAccount garante que o usuário autenticado só consulte a própria
+// conta. Sem claim, só passa com allowUnauthenticated (o handler já
+// recusou a requisição caso contrário).
+func canAccessAccount(req events.APIGatewayV2HTTPRequest, accountID string) bool {
+	authenticated := authenticatedAccountID(req)
+	if authenticated == "" {
+		return allowUnauthenticated
+	}
+	return strings.EqualFold(authenticated, accountID)
+}
+
+func unauthorizedResponse() events.APIGatewayV2HTTPResponse {
+	resp := errorResponse(http.StatusUnauthorized, "Autenticação obrigatória")
+	resp.Headers["WWW-Authenticate"] = "Bearer"
+	return resp
+}
+
+// =========================================================
+// 📤 Respostas HTTP
+// =========================================================
+func jsonResponse(status int, body any) events.APIGatewayV2HTTPResponse {
+	data, _ := json.Marshal(body)
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(data),
+	}
+}
+
+func errorResponse(status int, message string) events.APIGatewayV2HTTPResponse {
+	return jsonResponse(status, map[string]string{"message": message})
+}
+
+// =========================================================
+// 🔎 GET /transactions/{id}
+// =========================================================
+func findTransactionStatus(ctx context.Context, d *sql.DB, eventID string) (*TransactionStatus, error) {
+	var (
+		status          TransactionStatus
+		rawAmount       string
+		timestamp       time.Time
+		rejectionReason sql.NullString
+	)
+
+	err := d.QueryRowContext(ctx,
+		`SELECT user_id, type, amount::text, timestamp, status, rejection_reason
+		 FROM transactions WHERE event_id = $1`,
+		eventID,
+	).Scan(&status.AccountID, &status.Type, &rawAmount, &timestamp, &status.Status, &rejectionReason)
+	if err == nil {
+		amount, err := decimal.NewFromString(rawAmount)
+		if err != nil {
+			return nil, fmt.Errorf("valor inválido no banco: %w", err)
+		}
+		status.EventID = eventID
+		status.Amount = &amount
+		status.Timestamp = timestamp.UTC().Format(time.RFC3339)
+		status.Reason = rejectionReason.String
+		return &status, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Não gravada: verifica se o consumer descartou o evento
+	var lastFailedAt time.Time
+	err = d.QueryRowContext(ctx,
+		`SELECT reason, attempts, last_failed_at FROM transaction_failures WHERE event_id = $1`,
+		eventID,
+	).Scan(&status.Reason, &status.Attempts, &lastFailedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	failed := err == nil
+
+	// O outbox do producer conhece a conta dos eventos descartados e os
+	// aceitos que o consumer ainda não gravou
+	var entry *OutboxEntry
+	if outbox != nil {
+		if entry, err = outbox.Find(ctx, eventID); err != nil {
+			return nil, err
+		}
+	}
+
+	if failed {
+		status.EventID = eventID
+		status.Status = statusFailed
+		status.Timestamp = lastFailedAt.UTC().Format(time.RFC3339)
+		if entry != nil {
+			status.AccountID = entry.Event.UserID
+			status.Type = entry.Event.Type
+		}
+		return &status, nil
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	return acceptedStatus(eventID, entry), nil
+}
+
+// acceptedStatus descreve um evento que só existe no outbox. Uma entrada
+// dead nunca chegou ao broker, então a transação não vai ser processada.
+func acceptedStatus(eventID string, entry *OutboxEntry) *TransactionStatus {
+	status := &TransactionStatus{
+		EventID:   eventID,
+		Status:    statusAccepted,
+		AccountID: entry.Event.UserID,
+		Type:      entry.Event.Type,
+		Amount:    &entry.Event.Amount,
+		Timestamp: entry.Event.Timestamp,
+	}
+	if entry.Status == outboxDead {
+		status.Status = statusFailed
+		status.Reason = "publicação no broker esgotou as tentativas: " + entry.LastError
+		status.Attempts = entry.Attempts
+	}
+	return status
+}
+
+func getTransactionStatus(ctx context.Context, d *sql.DB, req events.APIGatewayV2HTTPRequest) events.APIGatewayV2HTTPResponse {
+	eventID := req.PathParameters["id"]
+	if !uuidPattern.MatchString(eventID) {
+		return errorResponse(http.StatusBadRequest, "ID de transação inválido")
+	}
+
+	status, err := findTransactionStatus(ctx, d, eventID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Erro ao consultar transação", "event_id", eventID, logging.KeyError, err)
+		return errorResponse(http.StatusInternalServerError, "Erro ao consultar transação")
+	}
+	// Transação de outra conta responde como inexistente, para não revelar
+	// quais IDs existem. Falha sem conta conhecida (evento fora do outbox)
+	// só é exibida sem JWT, em desenvolvimento local.
+	if status != nil && !canAccessAccount(req, status.AccountID) {
+		status = nil
+	}
+	if status == nil {
+		return errorResponse(http.StatusNotFound, "Transação não encontrada")
+	}
+
+	if status.Status == statusAccepted {
+		return jsonResponse(http.StatusAccepted, status)
+	}
+	return jsonResponse(http.StatusOK, status)
+}
+
+// =========================================================
+// 📬 Função Lambda — roteia pelas rotas do API Gateway (HTTP API)
+// =========================================================
+func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	slog.InfoContext(ctx, "FinOrbit Query invocado", "route", req.RouteKey)
+
+	if authenticatedAccountID(req) == "" && !allowUnauthenticated {
+		return unauthorizedResponse(), nil
+	}
+
+	d, err := getDB(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Banco indisponível", logging.KeyError, err)
+		return errorResponse(http.StatusServiceUnavailable, "Banco indisponível"), nil
+	}
+
+	switch req.RouteKey {
+	case "GET /transactions/{id}":
+		return getTransactionStatus(ctx, d, req), nil
+	case "GET /accounts/{id}/balance":
+		return getAccountBalance(ctx, d, req), nil
+	case "GET /accounts/{id}/statement":
+		return getAccountStatement(ctx, d, req), nil
+	default:
+		return errorResponse(http.StatusNotFound, "Rota não encontrada"), nil
+	}
+}
+
+// =========================================================
+// 🚀 Ponto de entrada da Lambda
+// =========================================================
+func main() {
+	if err := logging.Setup(); err != nil {
+		logging.Fatal("Erro ao configurar logs", err)
+	}
+	if os.Getenv("GO_ENV") == "test" {
+		slog.Info("Modo de teste, Lambda não será iniciado")
+		return
+	}
+
+	allowUnauthenticated = os.Getenv("ALLOW_UNAUTHENTICATED") == "true"
+	if allowUnauthenticated {
+		slog.Warn("ALLOW_UNAUTHENTICATED ligado: consultas aceitas sem JWT")
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		logging.Fatal("Erro ao carregar configuração AWS", err)
+	}
+	outbox = newOutboxReader(cfg)
+
+	dbCredentials, err = dbcreds.NewProvider(cfg)
+	if err != nil {
+		logging.Fatal("Erro ao configurar credenciais do banco", err)
+	}
+
+	lambda.Start(handler)
+}
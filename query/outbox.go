@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	txevents "finorbit/events"
+)
+
+// =========================================================
+// 📤 Outbox do producer (somente leitura)
+// =========================================================
+// Um evento aceito pelo producer fica no outbox (OUTBOX_TABLE) até o
+// consumer gravá-lo no banco. É por ele que a query responde "accepted"
+// para transações conhecidas mas ainda não processadas.
+const outboxDead = "dead"
+
+type OutboxEntry struct {
+	Event     txevents.TransactionEvent
+	Status    string
+	Attempts  int
+	LastError string
+}
+
+// OutboxReader busca uma entrada pelo event_id; devolve nil se não existir.
+type OutboxReader interface {
+	Find(ctx context.Context, eventID string) (*OutboxEntry, error)
+}
+
+// DynamoDBClient é o subconjunto do client usado na leitura (mock nos testes).
+type DynamoDBClient interface {
+	GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+}
+
+var outbox OutboxReader
+
+// newOutboxReader devolve nil sem OUTBOX_TABLE: o producer publica direto
+// e a query só conhece o que já está no banco.
+func newOutboxReader(cfg aws.Config) OutboxReader {
+	table := os.Getenv("OUTBOX_TABLE")
+	if table == "" {
+		return nil
+	}
+	return &dynamoOutbox{client: dynamodb.NewFromConfig(cfg), table: table}
+}
+
+type dynamoOutbox struct {
+	client DynamoDBClient
+	table  string
+}
+
+func (o *dynamoOutbox) Find(ctx context.Context, eventID string) (*OutboxEntry, error) {
+	out, err := o.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(o.table),
+		Key:       map[string]ddbtypes.AttributeValue{"event_id": &ddbtypes.AttributeValueMemberS{Value: eventID}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar outbox: %w", err)
+	}
+	if len(out.Item) == 0 {
+		return nil, nil
+	}
+
+	str := func(name string) string {
+		if v, ok := out.Item[name].(*ddbtypes.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+
+	event, err := txevents.Decode([]byte(str("payload")))
+	if err != nil {
+		return nil, fmt.Errorf("payload inválido no outbox: %w", err)
+	}
+	entry := &OutboxEntry{Event: event, Status: str("status"), LastError: str("last_error")}
+	if v, ok := out.Item["attempts"].(*ddbtypes.AttributeValueMemberN); ok {
+		entry.Attempts, _ = strconv.Atoi(v.Value)
+	}
+	return entry, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockDynamoDB devolve o item configurado e guarda a chave consultada.
+type mockDynamoDB struct {
+	item map[string]ddbtypes.AttributeValue
+	key  string
+}
+
+func (m *mockDynamoDB) GetItem(_ context.Context, input *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	m.key = input.Key["event_id"].(*ddbtypes.AttributeValueMemberS).Value
+	return &dynamodb.GetItemOutput{Item: m.item}, nil
+}
+
+func TestDynamoOutbox_Find(t *testing.T) {
+	client := &mockDynamoDB{item: map[string]ddbtypes.AttributeValue{
+		"event_id": &ddbtypes.AttributeValueMemberS{Value: testEventID},
+		"payload": &ddbtypes.AttributeValueMemberS{Value: `{"schema_version":1,"event_id":"` + testEventID +
+			`","user_id":"` + testAccountID + `","amount":"42.50","type":"withdraw","timestamp":"2025-11-07T00:00:00Z"}`},
+		"status":   &ddbtypes.AttributeValueMemberS{Value: "pending"},
+		"attempts": &ddbtypes.AttributeValueMemberN{Value: "2"},
+	}}
+	reader := &dynamoOutbox{client: client, table: "outbox"}
+
+	entry, err := reader.Find(context.Background(), testEventID)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if client.key != testEventID {
+		t.Errorf("Consultou a chave errada: %s", client.key)
+	}
+	if entry.Event.UserID != testAccountID || entry.Event.Type != "withdraw" || entry.Status != "pending" || entry.Attempts != 2 {
+		t.Errorf("Entrada inesperada: %+v", entry)
+	}
+}
+
+func TestDynamoOutbox_FindInexistente(t *testing.T) {
+	reader := &dynamoOutbox{client: &mockDynamoDB{}, table: "outbox"}
+
+	entry, err := reader.Find(context.Background(), testEventID)
+	if err != nil || entry != nil {
+		t.Errorf("Esperava nil sem erro, obteve %+v (%v)", entry, err)
+	}
+}
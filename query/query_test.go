@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/shopspring/decimal"
+
+	txevents "finorbit/events"
+)
+
+// =========================================================
+// 🧹 Reset global entre testes
+// =========================================================
+func resetDBSingleton() {
+	db = nil
+	connectDB = openDB
+	outbox = nil
+}
+
+func init() {
+	os.Setenv("GO_ENV", "test")
+	// A maioria dos testes não monta JWT; os de autorização religam a exigência
+	allowUnauthenticated = true
+}
+
+func requireAuthentication(t *testing.T) {
+	t.Helper()
+	allowUnauthenticated = false
+	t.Cleanup(func() { allowUnauthenticated = true })
+}
+
+func withClaim(req events.APIGatewayV2HTTPRequest, accountID string) events.APIGatewayV2HTTPRequest {
+	req.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+		JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+			Claims: map[string]string{"sub": accountID},
+		},
+	}
+	return req
+}
+
+// fakeOutbox simula o outbox do producer.
+type fakeOutbox map[string]*OutboxEntry
+
+func (f fakeOutbox) Find(_ context.Context, eventID string) (*OutboxEntry, error) {
+	return f[eventID], nil
+}
+
+func outboxEntry(status string) *OutboxEntry {
+	return &OutboxEntry{
+		Event: txevents.TransactionEvent{
+			EventID:   testEventID,
+			UserID:    testAccountID,
+			Amount:    decimal.RequireFromString("42.50"),
+			Type:      "deposit",
+			Timestamp: "2025-11-07T00:00:00Z",
+		},
+		Status: status,
+	}
+}
+
+const testEventID = "0b6f5e1c-3a8e-4c55-9f0e-8f1f4a2b7c11"
+
+func statusRequest(id string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		RouteKey:       "GET /transactions/{id}",
+		PathParameters: map[string]string{"id": id},
+	}
+}
+
+func decodeStatus(t *testing.T, resp events.APIGatewayV2HTTPResponse) TransactionStatus {
+	t.Helper()
+	var status TransactionStatus
+	if err := json.Unmarshal([]byte(resp.Body), &status); err != nil {
+		t.Fatalf("Corpo não é JSON válido: %v (%s)", err, resp.Body)
+	}
+	return status
+}
+
+// =========================================================
+// 🔎 GET /transactions/{id}
+// =========================================================
+func TestTransactionStatus_Persisted(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM transactions WHERE event_id`).WithArgs(testEventID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "type", "amount", "timestamp", "status", "rejection_reason"}).
+			AddRow("acc-1", "deposit", "150.25", time.Date(2025, 11, 7, 0, 0, 0, 0, time.UTC), "persisted", nil))
+
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	if resp.StatusCode != 200 {
+		t.Fatalf("Esperava 200, obteve %d", resp.StatusCode)
+	}
+
+	status := decodeStatus(t, resp)
+	if status.Status != statusPersisted || status.AccountID != "acc-1" || status.Amount.String() != "150.25" {
+		t.Errorf("Status inesperado: %+v", status)
+	}
+	if status.Timestamp != "2025-11-07T00:00:00Z" {
+		t.Errorf("Timestamp inesperado: %s", status.Timestamp)
+	}
+}
+
+func TestTransactionStatus_Rejected(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM transactions WHERE event_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "type", "amount", "timestamp", "status", "rejection_reason"}).
+			AddRow("acc-1", "withdraw", "50.00", time.Now(), "rejected", "saldo insuficiente"))
+
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	status := decodeStatus(t, resp)
+	if status.Status != statusRejected || status.Reason != "saldo insuficiente" {
+		t.Errorf("Esperava rejeição com motivo, obteve %+v", status)
+	}
+}
+
+func TestTransactionStatus_Failed(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM transactions WHERE event_id`).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery(`FROM transaction_failures WHERE event_id`).WithArgs(testEventID).
+		WillReturnRows(sqlmock.NewRows([]string{"reason", "attempts", "last_failed_at"}).
+			AddRow("tipo de transação desconhecido", 3, time.Now()))
+
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	status := decodeStatus(t, resp)
+	if status.Status != statusFailed || status.Attempts != 3 || status.Amount != nil {
+		t.Errorf("Esperava falha com 3 tentativas, obteve %+v", status)
+	}
+}
+
+func TestTransactionStatus_NotFound(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM transactions WHERE event_id`).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery(`FROM transaction_failures WHERE event_id`).WillReturnRows(sqlmock.NewRows([]string{"reason"}))
+
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	if resp.StatusCode != 404 {
+		t.Errorf("Esperava 404, obteve %d", resp.StatusCode)
+	}
+}
+
+func TestTransactionStatus_AceitaNoOutbox(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+	outbox = fakeOutbox{testEventID: outboxEntry("pending")}
+
+	mock.ExpectQuery(`FROM transactions WHERE event_id`).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery(`FROM transaction_failures WHERE event_id`).WillReturnRows(sqlmock.NewRows([]string{"reason"}))
+
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	if resp.StatusCode != 202 {
+		t.Fatalf("Esperava 202 para evento só no outbox, obteve %d", resp.StatusCode)
+	}
+	status := decodeStatus(t, resp)
+	if status.Status != statusAccepted || status.AccountID != testAccountID || status.Amount.String() != "42.5" {
+		t.Errorf("Status inesperado: %+v", status)
+	}
+}
+
+func TestTransactionStatus_OutboxDeadEhFalha(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+	entry := outboxEntry("dead")
+	entry.Attempts, entry.LastError = 10, "timeout"
+	outbox = fakeOutbox{testEventID: entry}
+
+	mock.ExpectQuery(`FROM transactions WHERE event_id`).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery(`FROM transaction_failures WHERE event_id`).WillReturnRows(sqlmock.NewRows([]string{"reason"}))
+
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	status := decodeStatus(t, resp)
+	if resp.StatusCode != 200 || status.Status != statusFailed || status.Attempts != 10 {
+		t.Errorf("Esperava falha de publicação, obteve %d %+v", resp.StatusCode, status)
+	}
+}
+
+// =========================================================
+// 🔐 Autorização
+// =========================================================
+func TestHandler_SemJWTRecusado(t *testing.T) {
+	resetDBSingleton()
+	requireAuthentication(t)
+
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	if resp.StatusCode != 401 || resp.Headers["WWW-Authenticate"] != "Bearer" {
+		t.Errorf("Esperava 401 com WWW-Authenticate, obteve %d %v", resp.StatusCode, resp.Headers)
+	}
+}
+
+func TestTransactionStatus_OutraContaNaoEncontrada(t *testing.T) {
+	resetDBSingleton()
+	requireAuthentication(t)
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM transactions WHERE event_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "type", "amount", "timestamp", "status", "rejection_reason"}).
+			AddRow(testAccountID, "deposit", "10.00", time.Now(), "persisted", nil))
+
+	resp, _ := handler(context.Background(), withClaim(statusRequest(testEventID), "0a0b0c0d-0000-4000-8000-000000000000"))
+	if resp.StatusCode != 404 {
+		t.Errorf("Esperava 404 para transação de outra conta, obteve %d", resp.StatusCode)
+	}
+}
+
+func TestTransactionStatus_PropriaContaComJWT(t *testing.T) {
+	resetDBSingleton()
+	requireAuthentication(t)
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM transactions WHERE event_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "type", "amount", "timestamp", "status", "rejection_reason"}).
+			AddRow(testAccountID, "deposit", "10.00", time.Now(), "persisted", nil))
+
+	resp, _ := handler(context.Background(), withClaim(statusRequest(testEventID), testAccountID))
+	if resp.StatusCode != 200 {
+		t.Errorf("Esperava 200 para a própria transação, obteve %d", resp.StatusCode)
+	}
+}
+
+func TestTransactionStatus_InvalidID(t *testing.T) {
+	resetDBSingleton()
+	dbMock, _, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	resp, _ := handler(context.Background(), statusRequest("abc"))
+	if resp.StatusCode != 400 {
+		t.Errorf("Esperava 400 para ID inválido, obteve %d", resp.StatusCode)
+	}
+}
+
+func TestTransactionStatus_DBError(t *testing.T) {
+	resetDBSingleton()
+	dbMock, mock, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	mock.ExpectQuery(`FROM transactions WHERE event_id`).WillReturnError(errors.New("connection refused"))
+
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	if resp.StatusCode != 500 {
+		t.Errorf("Esperava 500, obteve %d", resp.StatusCode)
+	}
+}
+
+// =========================================================
+// 🧭 Roteamento e inicialização
+// =========================================================
+func TestHandler_UnknownRoute(t *testing.T) {
+	resetDBSingleton()
+	dbMock, _, _ := sqlmock.New()
+	defer dbMock.Close()
+	db = dbMock
+
+	resp, _ := handler(context.Background(), events.APIGatewayV2HTTPRequest{RouteKey: "DELETE /transactions/{id}"})
+	if resp.StatusCode != 404 {
+		t.Errorf("Esperava 404 para rota desconhecida, obteve %d", resp.StatusCode)
+	}
+}
+
+func TestHandler_DBUnavailable(t *testing.T) {
+	resetDBSingleton()
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	if resp.StatusCode != 503 {
+		t.Errorf("Esperava 503 sem banco, obteve %d", resp.StatusCode)
+	}
+}
+
+// =========================================================
+// 🔁 Conexão com retentativas
+// =========================================================
+func TestGetDB_RetentaAteConectar(t *testing.T) {
+	resetDBSingleton()
+	savedDelay := dbConnectBaseDelay
+	dbConnectBaseDelay = time.Millisecond
+	dbMock, _, _ := sqlmock.New()
+	t.Cleanup(func() {
+		dbConnectBaseDelay = savedDelay
+		resetDBSingleton()
+		dbMock.Close()
+	})
+
+	calls := 0
+	connectDB = func(context.Context) (*sql.DB, error) {
+		calls++
+		if calls <= 2 {
+			return nil, errors.New("connection refused")
+		}
+		return dbMock, nil
+	}
+
+	if d, err := getDB(context.Background()); err != nil || d != dbMock {
+		t.Fatalf("Esperava conexão após retentativas, obteve %v", err)
+	}
+	getDB(context.Background())
+	if calls != 3 {
+		t.Errorf("Esperava 3 tentativas e a conexão reaproveitada, obteve %d", calls)
+	}
+}
+
+func TestGetDB_FalhaPermiteNovaTentativa(t *testing.T) {
+	resetDBSingleton()
+	savedDelay := dbConnectBaseDelay
+	dbConnectBaseDelay = time.Millisecond
+	t.Cleanup(func() {
+		dbConnectBaseDelay = savedDelay
+		resetDBSingleton()
+	})
+
+	calls := 0
+	connectDB = func(context.Context) (*sql.DB, error) {
+		calls++
+		return nil, errors.New("connection refused")
+	}
+
+	resp, _ := handler(context.Background(), statusRequest(testEventID))
+	if resp.StatusCode != 503 || calls != dbConnectAttempts {
+		t.Fatalf("Esperava 503 após %d tentativas, obteve %d após %d", dbConnectAttempts, resp.StatusCode, calls)
+	}
+	if db != nil {
+		t.Error("Falha não deveria deixar conexão no cache")
+	}
+}
+
+func TestMainFunction(t *testing.T) {
+	resetDBSingleton()
+	main() // não deve iniciar Lambda
+}